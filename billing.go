@@ -0,0 +1,146 @@
+package storekit
+
+import "time"
+
+// billingRetryPeriod is how long the App Store keeps attempting to renew a
+// subscription that failed to renew because of a billing issue.
+const billingRetryPeriod = 60 * 24 * time.Hour
+
+// BillingPeriod describes the latest paid period of the subscription to the
+// given product: when it started, when it ends, and whether the App Store will
+// renew it. A refunded period never renews. It returns false when the response
+// holds no subscription transaction for the product.
+func (r *ReceiptResponse) BillingPeriod(productID string) (periodStart, periodEnd time.Time, renews bool, ok bool) {
+	tx, ok := latestTransaction(r.transactions(), productID)
+	if !ok || tx.ExpiresDateMs == 0 {
+		return time.Time{}, time.Time{}, false, false
+	}
+
+	periodStart = timeFromMs(tx.PurchaseDateMs)
+	periodEnd = timeFromMs(tx.ExpiresDateMs)
+	if info, found := r.renewalInfo(productID); found {
+		renews = info.AutoRenewStatus == AutoRenewStatusOn && tx.CancellationDateMs == 0
+	}
+
+	return periodStart, periodEnd, renews, true
+}
+
+// BillingRetryWindow returns when the App Store started attempting to renew
+// the subscription to the given product, which is when its last period
+// expired, and the deadline after which it gives up. It returns false when the
+// subscription is not in billing retry.
+func (r *ReceiptResponse) BillingRetryWindow(productID string) (start, deadline time.Time, ok bool) {
+	return billingRetryWindow(productID, r.transactions(), r.PendingRenewalInfo)
+}
+
+// BillingRetryDeadline returns the time after which the App Store stops
+// attempting to renew the subscription to the given product. It returns false
+// when the subscription is not in billing retry.
+func (r *ReceiptResponse) BillingRetryDeadline(productID string) (time.Time, bool) {
+	_, deadline, ok := r.BillingRetryWindow(productID)
+	return deadline, ok
+}
+
+// GracePeriodEnd returns the time the billing grace period of the subscription
+// to the given product ends. Access continues until then while the App Store
+// attempts to renew the subscription, after which the subscription carries on
+// in billing retry without access. It returns false when the subscription is
+// not in a grace period.
+func (r *ReceiptResponse) GracePeriodEnd(productID string) (time.Time, bool) {
+	tx, ok := latestTransaction(r.transactions(), productID)
+	if !ok {
+		return time.Time{}, false
+	}
+	info, ok := renewalInfoOf(r.PendingRenewalInfo, tx)
+	if !ok {
+		return time.Time{}, false
+	}
+	return info.GracePeriodExpiresAt()
+}
+
+// BillingRetryWindow returns the billing retry window of the subscription to
+// the given product as described by the unified receipt. A DID_FAIL_TO_RENEW
+// notification opens the window and a DID_RECOVER or DID_RENEW notification
+// closes it.
+func (n *Notification) BillingRetryWindow(productID string) (start, deadline time.Time, ok bool) {
+	u := &n.UnifiedReceipt
+	return billingRetryWindow(productID, fromLatestReceiptInfo(u.LatestReceiptInfo), u.PendingRenewalInfo)
+}
+
+func billingRetryWindow(productID string, txs []InAppPurchaseReceipt, renewals []PendingRenewalInfo) (start, deadline time.Time, ok bool) {
+	tx, ok := latestTransaction(txs, productID)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	info, ok := renewalInfoOf(renewals, tx)
+	if !ok || !info.IsInBillingRetry() {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start = timeFromMs(tx.ExpiresDateMs)
+	return start, start.Add(billingRetryPeriod), true
+}
+
+// RenewalEvent is a single paid or free period of a subscription.
+type RenewalEvent struct {
+	TransactionId      string
+	WebOrderLineItemId string
+	ProductId          string
+
+	// The bounds of the period.
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+
+	// Whether the period was a free trial.
+	IsTrialPeriod bool
+
+	// Whether the period was an introductory price period.
+	IsInIntroOfferPeriod bool
+
+	// Whether the period started after a gap of at most the billing retry
+	// period following the previous period of the same subscription. The
+	// receipt does not record why a subscription lapsed, so this holds both
+	// for a subscription the App Store recovered from billing retry and for
+	// one the user resubscribed to within that window.
+	ResumedWithinRetryWindow bool
+
+	// Whether the period was refunded.
+	Refunded bool
+
+	// Whether the period was cut short by an upgrade to another product.
+	Upgraded bool
+}
+
+// RenewalEvents returns the periods of the subscriptions to the given product,
+// ordered by purchase date. Pass a response verified without excluding old
+// transactions to get the full history.
+func (r *ReceiptResponse) RenewalEvents(productID string) []RenewalEvent {
+	var events []RenewalEvent
+	previousEnd := make(map[string]time.Time)
+	for _, tx := range r.sortedTransactions() {
+		if tx.ProductId != productID || tx.ExpiresDateMs == 0 {
+			continue
+		}
+
+		e := RenewalEvent{
+			TransactionId:        tx.TransactionId,
+			WebOrderLineItemId:   tx.WebOrderLineItemId,
+			ProductId:            tx.ProductId,
+			PeriodStart:          timeFromMs(tx.PurchaseDateMs),
+			PeriodEnd:            timeFromMs(tx.ExpiresDateMs),
+			IsTrialPeriod:        tx.IsTrialPeriod.Bool(),
+			IsInIntroOfferPeriod: tx.IsInIntroOfferPeriod.Bool(),
+			Refunded:             tx.IsRefunded(),
+			Upgraded:             tx.IsUpgraded.Bool(),
+		}
+		if end, ok := previousEnd[tx.OriginalTransactionId]; ok {
+			gap := e.PeriodStart.Sub(end)
+			e.ResumedWithinRetryWindow = gap > 0 && gap <= billingRetryPeriod
+		}
+		previousEnd[tx.OriginalTransactionId] = e.PeriodEnd
+
+		events = append(events, e)
+	}
+
+	return events
+}
@@ -0,0 +1,41 @@
+package storekit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenewalEvents(t *testing.T) {
+	const day = int64(24 * time.Hour / time.Millisecond)
+	resp := &ReceiptResponse{
+		LatestReceiptInfo: []LatestReceiptInfo{
+			{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 30 * day},
+			{ProductId: "basic", TransactionId: "2", OriginalTransactionId: "1", PurchaseDateMs: 30 * day, ExpiresDateMs: 60 * day},
+			{ProductId: "basic", TransactionId: "3", OriginalTransactionId: "1", PurchaseDateMs: 70 * day, ExpiresDateMs: 100 * day, CancellationDateMs: 80 * day, IsUpgraded: true},
+			{ProductId: "basic", TransactionId: "4", OriginalTransactionId: "1", PurchaseDateMs: 200 * day, ExpiresDateMs: 230 * day, CancellationDateMs: 210 * day},
+		},
+	}
+
+	want := []struct {
+		transactionID string
+		resumed       bool
+		refunded      bool
+		upgraded      bool
+	}{
+		{"1", false, false, false},
+		{"2", false, false, false},
+		{"3", true, false, true},
+		{"4", false, true, false},
+	}
+
+	events := resp.RenewalEvents("basic")
+	if len(events) != len(want) {
+		t.Fatalf("want %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.TransactionId != w.transactionID || e.ResumedWithinRetryWindow != w.resumed || e.Refunded != w.refunded || e.Upgraded != w.upgraded {
+			t.Errorf("event %d: want %+v, got %+v", i, w, e)
+		}
+	}
+}
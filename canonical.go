@@ -0,0 +1,71 @@
+package storekit
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CanonicalSchemaVersion is the version of the representation produced by
+// MarshalCanonical. It changes whenever a field is renamed, removed or changes
+// meaning; adding fields keeps it.
+const CanonicalSchemaVersion = 1
+
+// CanonicalResponse is the versioned representation of a response produced by
+// MarshalCanonical.
+type CanonicalResponse struct {
+	SchemaVersion int `json:"schema_version"`
+
+	*NormalizedResponse
+}
+
+// MarshalCanonical encodes the normalized view of the response as a stable,
+// versioned JSON document suitable for long-term storage and diffing. The
+// same response always yields the same bytes: times are in UTC, transactions
+// are ordered by purchase date then transaction identifier, and pending
+// renewals by original transaction identifier then product identifier.
+func (r *ReceiptResponse) MarshalCanonical() ([]byte, error) {
+	n := r.Normalized()
+	sort.SliceStable(n.Transactions, func(i, j int) bool {
+		a, b := n.Transactions[i], n.Transactions[j]
+		if !timeEqual(a.PurchasedAt, b.PurchasedAt) {
+			return timeBefore(a.PurchasedAt, b.PurchasedAt)
+		}
+		return a.TransactionId < b.TransactionId
+	})
+	sort.SliceStable(n.PendingRenewals, func(i, j int) bool {
+		a, b := n.PendingRenewals[i], n.PendingRenewals[j]
+		if a.OriginalTransactionId != b.OriginalTransactionId {
+			return a.OriginalTransactionId < b.OriginalTransactionId
+		}
+		return a.ProductId < b.ProductId
+	})
+
+	data, err := json.Marshal(CanonicalResponse{
+		SchemaVersion:      CanonicalSchemaVersion,
+		NormalizedResponse: n,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal canonical response")
+	}
+
+	return data, nil
+}
+
+// timeEqual reports whether two optional times are the same.
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// timeBefore reports whether a precedes b, absent times coming first.
+func timeBefore(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
+}
@@ -3,10 +3,17 @@ package storekit
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
-	"unicode"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -16,9 +23,55 @@ const (
 	productionReceiptVerificationURL = "https://buy.itunes.apple.com/verifyReceipt"
 )
 
+// ResponsePersister receives the raw response body returned by the App Store.
+// The request key is the FingerprintReceipt of the verified receipt data, which
+// identifies it without carrying the receipt itself.
+type ResponsePersister func(ctx context.Context, requestKey string, raw []byte)
+
 type client struct {
-	verificationURL    string
+	environments       []Environment
 	autofixEnvironment bool
+	onEnvSwitch        func(from, to Environment)
+	requestDumper      func(reqJSON []byte)
+	responsePersister  ResponsePersister
+	redactedMetadata   []string
+	retryPolicy        RetryPolicy
+	expiredAsValid     bool
+	statusErrors       bool
+	clock              Clock
+
+	httpClient  *http.Client
+	httpClients map[Environment]*http.Client
+
+	endpoints    EndpointConfig
+	endpointsErr error
+
+	retryPolicies        map[Environment]RetryPolicy
+	nonRetryableStatuses map[ReceiptResponseStatus]bool
+	maxTotalAttempts     int
+	verifyTimeout        time.Duration
+
+	// jitterRand spreads the retries of the general internal error. It is
+	// seeded per client and is not safe for concurrent use, hence jitterMu.
+	jitterMu   sync.Mutex
+	jitterRand *rand.Rand
+
+	sharedSecret           string
+	sharedSecrets          map[Environment]string
+	sharedSecretVars       map[Environment]string
+	excludeOldTransactions bool
+
+	rejectSandboxInProduction bool
+	allowXcode                bool
+
+	bundleIDs  []string
+	appAppleID string
+
+	subscriptionProductIDs []string
+	maxResponseSkew        time.Duration
+
+	entitlementCache       EntitlementCache
+	entitlementCacheMargin time.Duration
 }
 
 // NewVerificationClient defaults to production verification URL with auto fix
@@ -29,20 +82,41 @@ type client struct {
 // looping.
 func NewVerificationClient() *client {
 	return &client{
-		verificationURL:    productionReceiptVerificationURL,
+		environments:       []Environment{EnvironmentProduction, EnvironmentSandbox},
 		autofixEnvironment: true,
+		clock:              realClock{},
+
+		nonRetryableStatuses: defaultNonRetryableStatuses(),
+		jitterRand:           rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
-// OnProductionEnv sets the client to use sandbox URL for verification.
+// OnSandboxEnv sets the client to use sandbox URL for verification.
 func (c *client) OnSandboxEnv() *client {
-	c.verificationURL = sandboxReceiptVerificationURL
-	return c
+	return c.WithEnvironmentOrder(EnvironmentSandbox, EnvironmentProduction)
 }
 
 // OnProductionEnv sets the client to use production URL for verification.
 func (c *client) OnProductionEnv() *client {
-	c.verificationURL = productionReceiptVerificationURL
+	return c.WithEnvironmentOrder(EnvironmentProduction, EnvironmentSandbox)
+}
+
+// WithEnvironmentOrder sets the environments the client verifies receipts
+// against, in order of preference. The first one receives the initial
+// request. Auto fix only resends to an environment listed after the one that
+// reported the incompatible receipt, so listing a single environment disables
+// resending. Environments without a verifyReceipt endpoint, such as
+// EnvironmentXcode, are ignored, as are calls left without environments.
+func (c *client) WithEnvironmentOrder(environments ...Environment) *client {
+	var verifiable []Environment
+	for _, env := range environments {
+		if hasVerificationEndpoint(env) {
+			verifiable = append(verifiable, env)
+		}
+	}
+	if len(verifiable) > 0 {
+		c.environments = verifiable
+	}
 	return c
 }
 
@@ -53,75 +127,671 @@ func (c *client) WithoutEnvAutoFix() *client {
 	return c
 }
 
+// WithEndpoints overrides the URLs of the App Store endpoints. A configuration
+// that does not pass EndpointConfig.Validate is not applied, and every
+// verification then fails with the validation error; call Validate beforehand
+// to catch it at startup.
+func (c *client) WithEndpoints(cfg EndpointConfig) *client {
+	if err := cfg.Validate(); err != nil {
+		c.endpointsErr = err
+		return c
+	}
+	c.endpoints = cfg
+	c.endpointsErr = nil
+	return c
+}
+
+// WithHTTPClient sets the HTTP client the requests to the App Store are sent
+// with. The client uses http.DefaultClient by default.
+func (c *client) WithHTTPClient(httpClient *http.Client) *client {
+	c.httpClient = httpClient
+	return c
+}
+
+// WithHTTPClientFor sets the HTTP client the requests to the given environment
+// are sent with, for instance to reach the sandbox through a development
+// proxy. When auto fix resends a receipt to the other environment, the request
+// goes through the client of that environment. Environments without one use
+// the client set with WithHTTPClient.
+func (c *client) WithHTTPClientFor(env Environment, httpClient *http.Client) *client {
+	if c.httpClients == nil {
+		c.httpClients = make(map[Environment]*http.Client)
+	}
+	c.httpClients[env] = httpClient
+	return c
+}
+
+// httpClientFor returns the HTTP client of the requests to the environment.
+func (c *client) httpClientFor(env Environment) *http.Client {
+	if httpClient, ok := c.httpClients[env]; ok {
+		return httpClient
+	}
+	if c.httpClient != nil {
+		return c.httpClient
+	}
+	return http.DefaultClient
+}
+
+// WithOnEnvironmentSwitch sets a hook called whenever auto fix resends a receipt
+// to another environment, with the environment the receipt was first sent to
+// and the one it is resent to. The hook runs right before the resend request,
+// so that callers can count switches and time the resend around it.
+func (c *client) WithOnEnvironmentSwitch(hook func(from, to Environment)) *client {
+	c.onEnvSwitch = hook
+	return c
+}
+
+// WithRequestDumper sets a hook that receives the JSON body of every request
+// right before it is first sent to an environment, as encoded by the client,
+// to debug malformed receipt data. The shared secret is replaced with
+// "REDACTED"; the rest is byte for byte what the App Store receives.
+// Retries of the same body are not dumped again.
+func (c *client) WithRequestDumper(dumper func(reqJSON []byte)) *client {
+	c.requestDumper = dumper
+	return c
+}
+
+// WithResponsePersister sets a hook that receives the exact bytes of every
+// response the App Store returned with a parsable status, including
+// rejections such as 21003 and responses that then fail the client-side
+// checks, so that failures can be investigated from the archive. Failed
+// requests and unparsable responses are not passed. When auto fix resends the
+// request, only the final response body is passed.
+//
+// The hook runs synchronously before Verify returns. Offload slow work such as
+// network writes to a separate goroutine.
+func (c *client) WithResponsePersister(persister ResponsePersister) *client {
+	c.responsePersister = persister
+	return c
+}
+
+// WithRedactedMetadataKeys keeps the given metadata keys, set on the context
+// with ContextWithMetadata, away from the hooks and the verification result.
+// Use it for sensitive values that must not reach logs or storage.
+func (c *client) WithRedactedMetadataKeys(keys ...string) *client {
+	c.redactedMetadata = append(c.redactedMetadata, keys...)
+	return c
+}
+
+// WithRetryPolicy sets how requests failing with an App Store server error
+// (HTTP 5xx) or a retryable status are retried. Client errors (HTTP 4xx) and
+// statuses set by WithNonRetryableStatuses are never retried. Retrying stops
+// early when the context is done.
+func (c *client) WithRetryPolicy(policy RetryPolicy) *client {
+	c.retryPolicy = policy
+	return c
+}
+
+// WithRetryPolicyFor sets the retry policy of the requests to the given
+// environment, overriding the one set by WithRetryPolicy. Use it to retry the
+// sandbox, which is far less reliable, more aggressively than production.
+func (c *client) WithRetryPolicyFor(env Environment, policy RetryPolicy) *client {
+	if c.retryPolicies == nil {
+		c.retryPolicies = make(map[Environment]RetryPolicy)
+	}
+	c.retryPolicies[env] = policy
+	return c
+}
+
+// retryPolicyFor returns the retry policy of the requests to the environment.
+func (c *client) retryPolicyFor(env Environment) RetryPolicy {
+	if policy, ok := c.retryPolicies[env]; ok {
+		return policy
+	}
+	return c.retryPolicy
+}
+
+// WithNonRetryableStatuses marks the given App Store statuses as terminal, so
+// that responses with them are returned right away instead of being retried.
+// Statuses that retrying cannot fix, such as a shared secret mismatch, are
+// terminal by default.
+func (c *client) WithNonRetryableStatuses(statuses ...ReceiptResponseStatus) *client {
+	for _, status := range statuses {
+		c.nonRetryableStatuses[status] = true
+	}
+	return c
+}
+
+// WithMaxTotalAttempts caps the number of requests a single verification sends
+// to the App Store, counting retries and the resend to another environment
+// together. Once the cap is reached, the last response or error is returned
+// as is. The number of requests is only bounded by the retry policies by
+// default.
+func (c *client) WithMaxTotalAttempts(n int) *client {
+	c.maxTotalAttempts = n
+	return c
+}
+
+// WithVerifyTimeout bounds the time a single verification may take, including
+// every retry and the resend to another environment. It applies on top of any
+// deadline of the context. Verification is only bounded by the context by
+// default.
+func (c *client) WithVerifyTimeout(d time.Duration) *client {
+	c.verifyTimeout = d
+	return c
+}
+
+// attemptsLeft reports whether the verification may send another request
+// after the given number of attempts.
+func (c *client) attemptsLeft(attempts int) bool {
+	return c.maxTotalAttempts <= 0 || attempts < c.maxTotalAttempts
+}
+
+// WithExpiredSubscriptionAsValid makes VerifyWithResult accept responses with
+// the 21006 status. The status means the receipt is valid but the subscription
+// it contains has expired, so it is not a failure to verify. The entitlement
+// helpers of the response report such subscriptions as expired.
+func (c *client) WithExpiredSubscriptionAsValid() *client {
+	c.expiredAsValid = true
+	return c
+}
+
+// WithStatusErrors makes Verify report responses with a non-zero status as a
+// *StatusError while still returning the body and the parsed response, so
+// that the data can be inspected along with the error. What the response
+// holds depends on the status:
+//
+//   - 21006: the receipt and the latest receipt info of the expired
+//     subscription. It is not reported when WithExpiredSubscriptionAsValid is
+//     set.
+//   - 21007 and 21008: the status only. Auto fix resends such receipts, so
+//     they are only reported when it is disabled or has no environment left.
+//   - 21010: the status only. The error matches ErrReceiptUnauthorized.
+//   - 21100-21199: the status and is-retryable.
+//   - Any other status: the status, and sometimes the environment.
+func (c *client) WithStatusErrors() *client {
+	c.statusErrors = true
+	return c
+}
+
+// WithClock sets the clock used for time-dependent behavior such as retry
+// backoff. The client uses the system clock by default.
+func (c *client) WithClock(clock Clock) *client {
+	c.clock = clock
+	return c
+}
+
+// WithSharedSecret sets the app’s shared secret used as the password of the
+// requests built by VerifyBase64.
+func (c *client) WithSharedSecret(secret string) *client {
+	c.sharedSecret = secret
+	return c
+}
+
+// WithSharedSecretFor sets the shared secret sent as the password of every
+// request to the given environment, replacing the password of the request.
+// Use it when the app has distinct sandbox and production secrets: when auto
+// fix resends a receipt to the other environment, the password is swapped to
+// the secret of that environment.
+func (c *client) WithSharedSecretFor(env Environment, secret string) *client {
+	if c.sharedSecrets == nil {
+		c.sharedSecrets = make(map[Environment]string)
+	}
+	c.sharedSecrets[env] = secret
+	return c
+}
+
+// WithSharedSecretFromEnv reads the shared secret of the requests from the
+// given environment variables, one for the sandbox and one for production,
+// picking the variable of the environment each request is sent to. The
+// variables are read at verification time. Secrets set with
+// WithSharedSecretFor and passwords set on the request take precedence; when
+// neither is set and the variable is unset or empty, verification fails
+// before calling the App Store. Pass an empty name to skip an environment.
+func (c *client) WithSharedSecretFromEnv(sandboxVar, productionVar string) *client {
+	c.sharedSecretVars = make(map[Environment]string)
+	if sandboxVar != "" {
+		c.sharedSecretVars[EnvironmentSandbox] = sandboxVar
+	}
+	if productionVar != "" {
+		c.sharedSecretVars[EnvironmentProduction] = productionVar
+	}
+	return c
+}
+
+// WithOldTransactionsExcluded makes the requests built by VerifyBase64 ask for
+// the latest renewal transaction of each subscription only.
+func (c *client) WithOldTransactionsExcluded() *client {
+	c.excludeOldTransactions = true
+	return c
+}
+
+// WithRejectSandboxInProduction makes a production client fail verification
+// with ErrSandboxReceiptRejected when the App Store reports that the receipt
+// comes from the sandbox, including after auto fix has resent it there. This
+// keeps test purchases, such as TestFlight ones, from granting real
+// entitlements.
+//
+// Note that App Review purchases also come from the sandbox, so enabling this
+// check fails receipts sent while your app is in review.
+func (c *client) WithRejectSandboxInProduction() *client {
+	c.rejectSandboxInProduction = true
+	return c
+}
+
+// WithXcodeReceiptsAllowed makes a sandbox client accept receipts generated by
+// StoreKit testing in Xcode. Verification fails with ErrXcodeReceiptRejected
+// for such receipts otherwise, and always on a production client, so that
+// locally signed test purchases never grant real entitlements. Enable it in
+// development builds only.
+func (c *client) WithXcodeReceiptsAllowed() *client {
+	c.allowXcode = true
+	return c
+}
+
+// WithAppContext sets the app the client verifies receipts for. Verification
+// then fails when the receipt belongs to another app:
+//
+//   - bundleID is compared with the bundle_id of the receipt, failing with
+//     ErrBundleIDMismatch.
+//   - appAppleID is compared with the app_item_id of the receipt, failing with
+//     ErrAppAppleIDMismatch. Apps only get this identifier in production, so
+//     receipts without it are not checked.
+//
+// Empty values skip the corresponding check. Responses with a status that does
+// not count as a successful verification carry no receipt and are not checked.
+func (c *client) WithAppContext(bundleID, appAppleID string) *client {
+	c.bundleIDs = nil
+	if bundleID != "" {
+		c.bundleIDs = []string{bundleID}
+	}
+	c.appAppleID = appAppleID
+	return c
+}
+
+// WithExpectedBundleIDs makes verification accept receipts whose bundle_id is
+// any of the given ones, for backends serving several apps. Other receipts
+// fail with ErrBundleIDMismatch, reporting the bundle identifier received.
+// The check runs after a successful verification only. It replaces the bundle
+// identifier set by WithAppContext.
+func (c *client) WithExpectedBundleIDs(bundleIDs ...string) *client {
+	c.bundleIDs = bundleIDs
+	return c
+}
+
+// WithSubscriptionProductIDs makes verification fail with a
+// *MissingExpiryError when a transaction of one of the given auto-renewable
+// subscription products has no expiration date, which points to a misclassified
+// product or a malformed response. The response is still returned along with
+// the error.
+func (c *client) WithSubscriptionProductIDs(productIDs ...string) *client {
+	c.subscriptionProductIDs = append(c.subscriptionProductIDs, productIDs...)
+	return c
+}
+
+// WithMaxResponseSkew makes verification fail with a *ResponseSkewError when
+// the time the App Store reports processing the request at is further than
+// the given tolerance from the client clock, which points to a wrong local
+// clock or a replayed response. Leave room for network latency and ordinary
+// clock drift; a few minutes is a reasonable tolerance. The response is still
+// returned along with the error.
+func (c *client) WithMaxResponseSkew(d time.Duration) *client {
+	c.maxResponseSkew = d
+	return c
+}
+
+// WithEntitlementCache makes VerifyEntitlement keep active subscription
+// entitlements in the given cache and return them without calling the App
+// Store until the margin before they expire. Set the margin to the time the
+// App Store may take to report a renewal or a cancellation that matters to
+// you.
+func (c *client) WithEntitlementCache(cache EntitlementCache, margin time.Duration) *client {
+	c.entitlementCache = cache
+	c.entitlementCacheMargin = margin
+	return c
+}
+
 func (c *client) isSandbox() bool {
-	return c.verificationURL == sandboxReceiptVerificationURL
+	return c.environments[0] == EnvironmentSandbox
 }
 
 func (c *client) isProduction() bool {
-	return c.verificationURL == productionReceiptVerificationURL
+	return c.environments[0] == EnvironmentProduction
+}
+
+// isAccepted reports whether the response status counts as a successful
+// verification.
+func (c *client) isAccepted(status ReceiptResponseStatus) bool {
+	return status == ReceiptResponseStatusOK ||
+		c.expiredAsValid && status == ReceiptResponseStatusValidButSubscriptionExpired
 }
 
 func (c *client) Verify(ctx context.Context, receiptRequest *ReceiptRequest) (body []byte, resp *ReceiptResponse, err error) {
+	return c.verifyBody(ctx, receiptRequest, parseResponse)
+}
+
+// verifyBody runs the verification for the methods returning the body and the
+// parsed response.
+func (c *client) verifyBody(ctx context.Context, receiptRequest *ReceiptRequest, parse responseParser) (body []byte, resp *ReceiptResponse, err error) {
+	result, err := c.verify(ctx, receiptRequest, parse)
+	if err != nil {
+		// Client-side checks fail once the response is parsed, which is then
+		// returned along with the error:
+		return result.Body, result.Response, err
+	}
+
+	if c.statusErrors && !c.isAccepted(result.Response.Status) {
+		err = newStatusError(result.Response.Status, receiptRequest)
+	}
+
+	return result.Body, result.Response, err
+}
+
+// VerifyLatestOnly verifies the receipt like Verify but decodes the response
+// as a stream, keeping only the latest transaction of each product in the
+// latest receipt info and skipping the in-app purchases of the receipt. Use it
+// for accounts with long renewal histories when only the current state
+// matters. The raw body is still returned in full.
+func (c *client) VerifyLatestOnly(ctx context.Context, receiptRequest *ReceiptRequest) (body []byte, resp *ReceiptResponse, err error) {
+	return c.verifyBody(ctx, receiptRequest, parseLatestOnly)
+}
+
+// VerifyBase64 verifies the base64 encoded receipt data as sent by the app. The
+// request uses the shared secret and the exclusion of old transactions set on
+// the client, unless overridden by the options.
+func (c *client) VerifyBase64(ctx context.Context, receiptData string, opts ...RequestOption) (body []byte, resp *ReceiptResponse, err error) {
+	return c.Verify(ctx, c.newReceiptRequest(receiptData, opts))
+}
+
+// VerifyRaw verifies the raw receipt bytes, as read from the app receipt file,
+// like VerifyBase64. The bytes are encoded with standard base64 before being
+// sent, so do not pass receipt data that is already encoded.
+func (c *client) VerifyRaw(ctx context.Context, receipt []byte, opts ...RequestOption) (body []byte, resp *ReceiptResponse, err error) {
+	return c.Verify(ctx, c.newReceiptRequest(base64.StdEncoding.EncodeToString(receipt), opts))
+}
+
+// VerifyReader verifies the base64 encoded receipt data read from r, such as
+// an upload body, like VerifyBase64. The data is read into the request in a
+// single pass, without an intermediate copy, and surrounding whitespace is
+// trimmed.
+func (c *client) VerifyReader(ctx context.Context, r io.Reader, opts ...RequestOption) (body []byte, resp *ReceiptResponse, err error) {
+	var receiptData strings.Builder
+	if _, err = io.Copy(&receiptData, r); err != nil {
+		err = errors.Wrap(err, "could not read receipt data")
+		return
+	}
+
+	return c.Verify(ctx, c.newReceiptRequest(strings.TrimSpace(receiptData.String()), opts))
+}
+
+func (c *client) newReceiptRequest(receiptData string, opts []RequestOption) *ReceiptRequest {
+	req := &ReceiptRequest{
+		ReceiptData:            receiptData,
+		Password:               c.sharedSecret,
+		ExcludeOldTransactions: c.excludeOldTransactions,
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	return req
+}
+
+// VerifyWithResult verifies the receipt like Verify but gathers everything
+// about the verification into a single value. The error of the result is set
+// both on failed requests and on responses with a non-zero status.
+func (c *client) VerifyWithResult(ctx context.Context, receiptRequest *ReceiptRequest) *VerifyResult {
+	result, err := c.verify(ctx, receiptRequest, parseResponse)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if !c.isAccepted(result.Response.Status) {
+		result.Err = newStatusError(result.Response.Status, receiptRequest)
+	}
+
+	return result
+}
+
+// responseParser decodes the App Store response body.
+type responseParser func(body []byte) (*ReceiptResponse, error)
+
+func (c *client) verify(ctx context.Context, receiptRequest *ReceiptRequest, parse responseParser) (result *VerifyResult, err error) {
+	ctx = contextWithoutMetadata(ctx, c.redactedMetadata)
+	if c.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.verifyTimeout)
+		defer cancel()
+	}
+
+	result = &VerifyResult{}
+	result.RequestID, _ = RequestIDFromContext(ctx)
+	result.Metadata = MetadataFromContext(ctx)
+	if c.endpointsErr != nil {
+		err = c.endpointsErr
+		return
+	}
+
 	// Prepare request:
-	reqJSON, err := json.Marshal(receiptRequest)
+	env := c.environments[0]
+	reqJSON, err := c.marshalRequest(receiptRequest, env)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not marshal receipt request")
+		return
 	}
-	buf := bytes.NewReader(reqJSON)
 
 	// Dial the App Store server:
-	body, resp, err = c.queryStore(ctx, buf, c.verificationURL)
+	body, resp, err := c.queryStore(ctx, reqJSON, env, &result.Attempts, parse)
 	if err != nil {
 		return
 	}
 
-	// Resend to the secondary url if the primary one is wrong:
+	// Resend to the secondary environment if the primary one is wrong:
 	if c.autofixEnvironment {
-		resendNeeded, newUrl := c.checkResendNeeded(resp)
+		resendNeeded, newEnv := c.checkResendNeeded(resp, env)
 
-		if resendNeeded {
-			buf = bytes.NewReader(reqJSON)
-			body, resp, err = c.queryStore(ctx, buf, newUrl)
+		if resendNeeded && c.attemptsLeft(result.Attempts) {
+			if c.onEnvSwitch != nil {
+				c.onEnvSwitch(env, newEnv)
+			}
+			env = newEnv
+			reqJSON, err = c.marshalRequest(receiptRequest, env)
+			if err != nil {
+				return
+			}
+			body, resp, err = c.queryStore(ctx, reqJSON, env, &result.Attempts, parse)
+			if err != nil {
+				return
+			}
 		}
 	}
 
+	result.Body = body
+	result.Response = resp
+	result.Environment = env
+
+	if c.responsePersister != nil {
+		c.responsePersister(ctx, FingerprintReceipt(receiptRequest.ReceiptData), body)
+	}
+
+	// Responses with an error status carry no receipt to check; the status
+	// reports the failure:
+	if c.isAccepted(resp.Status) {
+		err = c.validate(resp)
+	}
 	return
 }
 
-// Send prepared request to Appstore and parse the response:
-func (c *client) queryStore(ctx context.Context, requestBuf *bytes.Reader, url string) (body []byte, resp *ReceiptResponse, err error) {
-	body, err = c.post(ctx, requestBuf, url)
+// marshalRequest encodes the request for the given environment, using the
+// shared secret configured for it if any.
+func (c *client) marshalRequest(receiptRequest *ReceiptRequest, env Environment) ([]byte, error) {
+	if secret, ok := c.sharedSecrets[env]; ok {
+		req := *receiptRequest
+		req.Password = secret
+		receiptRequest = &req
+	} else if name, ok := c.sharedSecretVars[env]; ok && receiptRequest.Password == "" {
+		secret := os.Getenv(name)
+		if secret == "" {
+			return nil, errors.Errorf("shared secret variable %s of the %s environment is not set", name, env)
+		}
+		req := *receiptRequest
+		req.Password = secret
+		receiptRequest = &req
+	}
+
+	reqJSON, err := json.Marshal(receiptRequest)
 	if err != nil {
-		return
+		return nil, errors.Wrap(err, "could not marshal receipt request")
+	}
+
+	return reqJSON, nil
+}
+
+// redactedPassword replaces the shared secret in dumped requests.
+const redactedPassword = "REDACTED"
+
+// redactRequest returns the encoded request with its password redacted.
+func redactRequest(reqJSON []byte) []byte {
+	var req ReceiptRequest
+	if err := json.Unmarshal(reqJSON, &req); err != nil {
+		return nil
+	}
+	if req.Password == "" {
+		return reqJSON
 	}
+	req.Password = redactedPassword
 
-	resp, err = parseResponse(body)
+	redacted, err := json.Marshal(&req)
 	if err != nil {
-		return
+		return nil
 	}
+	return redacted
+}
 
-	return
+// validate applies the client-side checks to a response that the App Store
+// returned successfully. It must only run on accepted statuses.
+func (c *client) validate(resp *ReceiptResponse) error {
+	if c.rejectSandboxInProduction && c.isProduction() && resp.Environment == EnvironmentSandbox {
+		return ErrSandboxReceiptRejected
+	}
+	if resp.IsXcodeEnvironment() && !(c.allowXcode && c.isSandbox()) {
+		return ErrXcodeReceiptRejected
+	}
+	if len(c.bundleIDs) > 0 && !containsString(c.bundleIDs, resp.Receipt.BundleId) {
+		return errors.Wrapf(ErrBundleIDMismatch, "receipt bundle id %q", resp.Receipt.BundleId)
+	}
+	if c.appAppleID != "" && resp.Receipt.AppItemId != 0 && strconv.FormatInt(resp.Receipt.AppItemId, 10) != c.appAppleID {
+		return errors.Wrapf(ErrAppAppleIDMismatch, "receipt app item id %d", resp.Receipt.AppItemId)
+	}
+	if c.maxResponseSkew > 0 && resp.Receipt.RequestDateMs != 0 {
+		requestedAt, now := timeFromMs(resp.Receipt.RequestDateMs), c.clock.Now()
+		if skew := now.Sub(requestedAt); skew > c.maxResponseSkew || -skew > c.maxResponseSkew {
+			return &ResponseSkewError{RequestedAt: requestedAt, LocalTime: now}
+		}
+	}
+	if len(c.subscriptionProductIDs) > 0 {
+		for _, tx := range resp.sortedTransactions() {
+			if tx.ExpiresDateMs == 0 && containsString(c.subscriptionProductIDs, tx.ProductId) {
+				return &MissingExpiryError{ProductId: tx.ProductId, TransactionId: tx.TransactionId}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Send prepared request to Appstore and parse the response. Server errors and
+// retryable statuses are retried according to the retry policy of the
+// environment, counting every request in attempts. Once retries or the total
+// attempt budget run out, the last response is returned as is:
+func (c *client) queryStore(ctx context.Context, reqJSON []byte, env Environment, attempts *int, parse responseParser) (body []byte, resp *ReceiptResponse, err error) {
+	url := c.endpoints.verificationURL(env)
+	httpClient := c.httpClientFor(env)
+	policy := c.retryPolicyFor(env)
+	if c.requestDumper != nil {
+		c.requestDumper(redactRequest(reqJSON))
+	}
+	for retry := 0; ; retry++ {
+		*attempts++
+		body, err = c.post(ctx, httpClient, bytes.NewReader(reqJSON), url)
+		if err == nil {
+			resp, err = parse(body)
+			if err != nil || !c.isRetryableStatus(resp) {
+				return
+			}
+		} else if !isServerError(err) {
+			return
+		}
+
+		maxRetries, delay := policy.MaxRetries, policy.delay(retry)
+		if err == nil && resp.Status == ReceiptResponseStatusInternalError {
+			// Apple asks to retry the general internal error even when other
+			// failures are not retried, spreading the retries of clients hit by
+			// the same outage:
+			if maxRetries < internalErrorRetries {
+				maxRetries = internalErrorRetries
+			}
+			if delay == 0 {
+				delay = internalErrorBackoff
+			}
+			delay = c.jitter(delay)
+		}
+		if retry >= maxRetries || !c.attemptsLeft(*attempts) {
+			return
+		}
+		if err = sleep(ctx, c.clock, delay); err != nil {
+			return
+		}
+	}
+}
+
+// isRetryableStatus reports whether the response status calls for sending the
+// request again. Internal data access errors are only retried when the App
+// Store flags them as retryable, except for the general 21199 error, which
+// Apple recommends retrying.
+func (c *client) isRetryableStatus(resp *ReceiptResponse) bool {
+	switch {
+	case resp.Status == ReceiptResponseStatusOK || c.nonRetryableStatuses[resp.Status]:
+		return false
+	case resp.Status == ReceiptResponseStatusInternalError:
+		return true
+	case resp.Status.isInternalError():
+		return resp.IsRetryable
+	default:
+		return true
+	}
 }
 
 func parseResponse(body []byte) (*ReceiptResponse, error) {
+	body = stripControlCharacters(body)
+
+	// A payload without a status would otherwise decode as a valid receipt:
+	var envelope struct {
+		Status *ReceiptResponseStatus `json:"status"`
+	}
+	err := json.Unmarshal(body, &envelope)
+	if err != nil {
+		return nil, newDecodeError(err)
+	}
+	if envelope.Status == nil {
+		return nil, newDecodeError(errors.New("missing status"))
+	}
+
 	resp := &ReceiptResponse{}
-	err := json.Unmarshal(
-		bytes.Map(func(r rune) rune {
-			if unicode.IsControl(r) {
-				return -1
-			}
-			return r
-		}, body),
-		resp,
-	)
+	err = json.Unmarshal(body, resp)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not unmarshal app store response")
+		return nil, newDecodeError(err)
 	}
 
 	return resp, nil
 }
 
-func (c *client) post(ctx context.Context, requestBuf *bytes.Reader, url string) ([]byte, error) {
+// stripControlCharacters drops the ASCII control characters the App Store
+// occasionally leaves in its JSON payloads, keeping the tab, line feed and
+// carriage return whitespace. Such bytes never occur within multi-byte UTF-8
+// sequences, so the rest of the payload is left untouched.
+func stripControlCharacters(body []byte) []byte {
+	stripped := make([]byte, 0, len(body))
+	for _, b := range body {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			continue
+		}
+		stripped = append(stripped, b)
+	}
+	return stripped
+}
+
+func (c *client) post(ctx context.Context, httpClient *http.Client, requestBuf *bytes.Reader, url string) ([]byte, error) {
 	req, err := http.NewRequest("POST", url, requestBuf)
 	if err != nil {
 		return nil, err
@@ -129,14 +799,15 @@ func (c *client) post(ctx context.Context, requestBuf *bytes.Reader, url string)
 
 	req.Header.Set("Content-Type", "application/json")
 	req = req.WithContext(ctx)
-	r, err := http.DefaultClient.Do(req)
+	r, err := httpClient.Do(req)
 	if err != nil {
 		// TODO: Handle this error (and probably retry at least once):
 		//       Post https://sandbox.itunes.apple.com/verifyReceipt: read tcp 10.1.11.101:36372->17.154.66.159:443: read: connection reset by peer
 		return nil, errors.Wrap(err, "could not connect to app store server")
 	}
+	defer r.Body.Close()
 	if r.StatusCode != http.StatusOK {
-		return nil, errors.New("app store http error (" + r.Status + ")")
+		return nil, &HTTPError{StatusCode: r.StatusCode, Status: r.Status}
 	}
 
 	// Parse response:
@@ -148,31 +819,50 @@ func (c *client) post(ctx context.Context, requestBuf *bytes.Reader, url string)
 	return body, nil
 }
 
-func (c *client) checkResendNeeded(resp *ReceiptResponse) (resendNeeded bool, newUrl string) {
+func (c *client) checkResendNeeded(resp *ReceiptResponse, current Environment) (resendNeeded bool, newEnv Environment) {
 	resendNeeded = false
 
 	switch resp.Status {
 	case ReceiptResponseStatusSandboxReceiptSentToProduction:
 		// On a 21007 status, retry the request in the sandbox environment:
-		if c.isProduction() {
+		if c.fallsBackTo(current, EnvironmentSandbox) {
 			resendNeeded = true
-			newUrl = sandboxReceiptVerificationURL
+			newEnv = EnvironmentSandbox
 		}
 	case ReceiptResponseStatusProductionReceiptSentToSandbox:
 		// On a 21008 status, retry the request in the production environment:
-		if c.isSandbox() {
+		if c.fallsBackTo(current, EnvironmentProduction) {
 			resendNeeded = true
-			newUrl = productionReceiptVerificationURL
+			newEnv = EnvironmentProduction
 		}
 	default:
-		// TODO: Retry at least once when an App Store internal error occurs here:
-		// 	if resp.Status >= 21100 && resp.Status <= 21199 {
-		// 		if resp.IsRetryable {
-		// 			goto post
-		// 		}
-		// 	}
+		// Other failures are retried by queryStore:
 		break
 	}
 
 	return
 }
+
+// fallsBackTo reports whether the environment order lists target after
+// current.
+func (c *client) fallsBackTo(current, target Environment) bool {
+	seenCurrent := false
+	for _, env := range c.environments {
+		if env == target && seenCurrent {
+			return true
+		}
+		if env == current {
+			seenCurrent = true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,499 @@
+package storekit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// stubResponse is a canned reply of the stub App Store.
+type stubResponse struct {
+	code int
+	body string
+}
+
+// stubRequest is a request received by the stub App Store.
+type stubRequest struct {
+	env Environment
+	req ReceiptRequest
+}
+
+// stubStore serves canned replies in order, whichever environment they are
+// requested from, and records the requests it receives.
+type stubStore struct {
+	t         *testing.T
+	server    *httptest.Server
+	mu        sync.Mutex
+	responses []stubResponse
+	requests  []stubRequest
+}
+
+func newStubStore(t *testing.T, responses ...stubResponse) *stubStore {
+	s := &stubStore{t: t, responses: responses}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubStore) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recorded := stubRequest{env: Environment(strings.TrimPrefix(r.URL.Path, "/"))}
+	if err := json.NewDecoder(r.Body).Decode(&recorded.req); err != nil {
+		s.t.Errorf("could not decode request: %v", err)
+	}
+	s.requests = append(s.requests, recorded)
+
+	if len(s.requests) > len(s.responses) {
+		s.t.Errorf("unexpected request %d", len(s.requests))
+		w.WriteHeader(http.StatusTeapot)
+		return
+	}
+	resp := s.responses[len(s.requests)-1]
+	if resp.code == 0 {
+		resp.code = http.StatusOK
+	}
+	w.WriteHeader(resp.code)
+	_, _ = w.Write([]byte(resp.body))
+}
+
+// client returns a production client sending its requests to the stub.
+func (s *stubStore) client() *client {
+	return NewVerificationClient().WithEndpoints(EndpointConfig{
+		SandboxVerifyURL:    s.server.URL + "/" + string(EnvironmentSandbox),
+		ProductionVerifyURL: s.server.URL + "/" + string(EnvironmentProduction),
+	})
+}
+
+func (s *stubStore) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func TestVerifyReturnsResponseWithMissingExpiryError(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":0,"latest_receipt_info":[{"product_id":"monthly","transaction_id":"1"}]}`})
+	c := store.client().WithSubscriptionProductIDs("monthly")
+
+	body, resp, err := c.Verify(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	var missing *MissingExpiryError
+	if !errors.As(err, &missing) || missing.TransactionId != "1" {
+		t.Fatalf("want a missing expiry error for transaction 1, got %v", err)
+	}
+	if body == nil || resp == nil {
+		t.Fatalf("want the response along with the error, got body %q and response %v", body, resp)
+	}
+}
+
+// fakeClock tells a fixed time and records the waits without sleeping.
+type fakeClock struct {
+	now   time.Time
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+	return ch
+}
+
+func TestVerifyReturnsResponseWithResponseSkewError(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":0,"receipt":{"request_date_ms":"1600000000000"}}`})
+	clock := &fakeClock{now: time.Unix(1600000000, 0).Add(time.Hour)}
+	c := store.client().WithClock(clock).WithMaxResponseSkew(5 * time.Minute)
+
+	body, resp, err := c.Verify(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	var skew *ResponseSkewError
+	if !errors.As(err, &skew) {
+		t.Fatalf("want a response skew error, got %v", err)
+	}
+	if body == nil || resp == nil {
+		t.Fatalf("want the response along with the error, got body %q and response %v", body, resp)
+	}
+}
+
+func TestVerifyAcceptsResponseWithinSkew(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":0,"receipt":{"request_date_ms":"1600000000000"}}`})
+	clock := &fakeClock{now: time.Unix(1600000000, 0).Add(-time.Minute)}
+	c := store.client().WithClock(clock).WithMaxResponseSkew(5 * time.Minute)
+
+	if _, _, err := c.Verify(context.Background(), &ReceiptRequest{ReceiptData: "receipt"}); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+}
+
+func TestVerifyRetriesServerErrors(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{code: http.StatusServiceUnavailable},
+		stubResponse{code: http.StatusServiceUnavailable},
+		stubResponse{body: `{"status":0}`},
+	)
+	clock := &fakeClock{}
+	c := store.client().WithClock(clock).WithRetryPolicy(RetryPolicy{MaxRetries: 2, Backoff: time.Second})
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if !result.OK() {
+		t.Fatalf("want success on the third attempt, got %v", result.Err)
+	}
+	if result.Attempts != 3 || store.requestCount() != 3 {
+		t.Fatalf("want 3 attempts, got %d and %d requests", result.Attempts, store.requestCount())
+	}
+	if len(clock.waits) != 2 || clock.waits[0] != time.Second || clock.waits[1] != 2*time.Second {
+		t.Fatalf("want backoffs of 1s and 2s, got %v", clock.waits)
+	}
+}
+
+func TestVerifyReturnsServerErrorOnceRetriesRunOut(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{code: http.StatusServiceUnavailable},
+		stubResponse{code: http.StatusServiceUnavailable},
+	)
+	c := store.client().WithClock(&fakeClock{}).WithRetryPolicy(RetryPolicy{MaxRetries: 1})
+
+	_, _, err := c.Verify(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("want the 503 error, got %v", err)
+	}
+}
+
+func TestVerifyWithResultLeavesResponseNilOnFailedRequests(t *testing.T) {
+	store := newStubStore(t, stubResponse{code: http.StatusServiceUnavailable})
+	c := store.client().WithClock(&fakeClock{})
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if result.OK() || result.Response != nil || result.Err == nil {
+		t.Fatalf("want a failed result without a response, got %+v", result)
+	}
+}
+
+func TestVerifyCapsAttemptsAcrossResendAndRetries(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{body: `{"status":21007}`},
+		stubResponse{code: http.StatusServiceUnavailable},
+		stubResponse{code: http.StatusServiceUnavailable},
+	)
+	c := store.client().
+		WithClock(&fakeClock{}).
+		WithRetryPolicy(RetryPolicy{MaxRetries: 5}).
+		WithMaxTotalAttempts(3)
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if result.Attempts != 3 || store.requestCount() != 3 {
+		t.Fatalf("want 3 attempts, got %d and %d requests", result.Attempts, store.requestCount())
+	}
+	if store.requests[0].env != EnvironmentProduction || store.requests[1].env != EnvironmentSandbox {
+		t.Fatalf("want the receipt resent to the sandbox, got requests %+v", store.requests)
+	}
+	var httpErr *HTTPError
+	if !errors.As(result.Err, &httpErr) {
+		t.Fatalf("want the last 503 error, got %v", result.Err)
+	}
+}
+
+func TestVerifySkipsResendWhenAttemptsRunOut(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{code: http.StatusServiceUnavailable},
+		stubResponse{body: `{"status":21007}`},
+	)
+	c := store.client().
+		WithClock(&fakeClock{}).
+		WithRetryPolicy(RetryPolicy{MaxRetries: 5}).
+		WithMaxTotalAttempts(2)
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if store.requestCount() != 2 {
+		t.Fatalf("want 2 requests, got %d", store.requestCount())
+	}
+	if result.Response.Status != ReceiptResponseStatusSandboxReceiptSentToProduction {
+		t.Fatalf("want the 21007 response returned as is, got %v", result.Err)
+	}
+}
+
+func TestVerifyRetriesGeneralInternalErrorByDefault(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{body: `{"status":21199}`},
+		stubResponse{body: `{"status":0}`},
+	)
+	clock := &fakeClock{}
+	c := store.client().WithClock(clock)
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if !result.OK() || result.Attempts != 2 {
+		t.Fatalf("want success on the second attempt, got %v after %d attempts", result.Err, result.Attempts)
+	}
+	if len(clock.waits) != 1 || clock.waits[0] < internalErrorBackoff/2 || clock.waits[0] > internalErrorBackoff {
+		t.Fatalf("want a single jittered backoff of at most %v, got %v", internalErrorBackoff, clock.waits)
+	}
+}
+
+func TestVerifyRetriesGeneralInternalErrorUpToPolicy(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{body: `{"status":21199}`},
+		stubResponse{body: `{"status":21199}`},
+		stubResponse{body: `{"status":0}`},
+	)
+	clock := &fakeClock{}
+	c := store.client().WithClock(clock).WithRetryPolicy(RetryPolicy{MaxRetries: 2, Backoff: 4 * time.Second})
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if !result.OK() || result.Attempts != 3 {
+		t.Fatalf("want success on the third attempt, got %v after %d attempts", result.Err, result.Attempts)
+	}
+	wants := []time.Duration{4 * time.Second, 8 * time.Second}
+	if len(clock.waits) != len(wants) {
+		t.Fatalf("want %d backoffs, got %v", len(wants), clock.waits)
+	}
+	for i, want := range wants {
+		if clock.waits[i] < want/2 || clock.waits[i] > want {
+			t.Fatalf("want backoff %d within [%v, %v], got %v", i, want/2, want, clock.waits[i])
+		}
+	}
+}
+
+func TestVerifyDoesNotRetryOtherInternalErrorsByDefault(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":21100,"is-retryable":true}`})
+	c := store.client().WithClock(&fakeClock{})
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if result.Attempts != 1 || result.Response.Status != 21100 {
+		t.Fatalf("want the 21100 response after a single attempt, got %d attempts", result.Attempts)
+	}
+}
+
+func TestStripControlCharacters(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"ascii controls", "{\x00\"status\"\x1f:0\x07}", `{"status":0}`},
+		{"whitespace kept", "{\t\"status\":\r\n0}", "{\t\"status\":\r\n0}"},
+		{"next line kept", "{\"bundle_id\":\"a\u0085b\"}", "{\"bundle_id\":\"a\u0085b\"}"},
+		{"multi-byte runes kept", "{\"bundle_id\":\"é ✓\"}", "{\"bundle_id\":\"é ✓\"}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripControlCharacters([]byte(tt.body))); got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseResponseKeepsControlAdjacentRunes(t *testing.T) {
+	resp, err := parseResponse([]byte("{\"status\":0,\"receipt\":{\"bundle_id\":\"com.example\u0085app\x01\"}}"))
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if resp.Receipt.BundleId != "com.example\u0085app" {
+		t.Fatalf("want U+0085 preserved, got %q", resp.Receipt.BundleId)
+	}
+}
+
+func TestVerifySwapsSharedSecretOnResend(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{body: `{"status":21007}`},
+		stubResponse{body: `{"status":0,"environment":"Sandbox"}`},
+	)
+	c := store.client().
+		WithSharedSecretFor(EnvironmentProduction, "production-secret").
+		WithSharedSecretFor(EnvironmentSandbox, "sandbox-secret")
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt", Password: "request-secret"})
+
+	if !result.OK() || result.Environment != EnvironmentSandbox {
+		t.Fatalf("want the receipt verified in the sandbox, got %v in %s", result.Err, result.Environment)
+	}
+	if len(store.requests) != 2 {
+		t.Fatalf("want 2 requests, got %d", len(store.requests))
+	}
+	for i, want := range []stubRequest{
+		{env: EnvironmentProduction, req: ReceiptRequest{ReceiptData: "receipt", Password: "production-secret"}},
+		{env: EnvironmentSandbox, req: ReceiptRequest{ReceiptData: "receipt", Password: "sandbox-secret"}},
+	} {
+		if store.requests[i] != want {
+			t.Fatalf("want request %d to be %+v, got %+v", i, want, store.requests[i])
+		}
+	}
+}
+
+func TestVerifyKeepsRequestPasswordWithoutEnvironmentSecret(t *testing.T) {
+	store := newStubStore(t,
+		stubResponse{body: `{"status":21007}`},
+		stubResponse{body: `{"status":0}`},
+	)
+	c := store.client().WithSharedSecretFor(EnvironmentProduction, "production-secret")
+
+	c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt", Password: "request-secret"})
+
+	if got := store.requests[1].req.Password; got != "request-secret" {
+		t.Fatalf("want the request password sent to the sandbox, got %q", got)
+	}
+}
+
+// parsers are the decoders of App Store responses, which must agree on what
+// they reject.
+var parsers = []struct {
+	name  string
+	parse responseParser
+}{
+	{"parseResponse", parseResponse},
+	{"parseLatestOnly", parseLatestOnly},
+}
+
+// malformedPayloads are response bodies both parsers must reject, along with
+// the field the error names, if any.
+var malformedPayloads = []struct {
+	name      string
+	body      string
+	wantField string
+}{
+	{"empty body", ``, ""},
+	{"not json", `<html>Service Unavailable</html>`, ""},
+	{"truncated", `{"status":0,"receipt":{`, ""},
+	{"top-level array", `[{"status":0}]`, ""},
+	{"missing status", `{"environment":"Production","receipt":{}}`, ""},
+	{"null status", `{"status":null}`, ""},
+	{"string status", `{"status":"0"}`, "status"},
+	{"number for string", `{"status":0,"receipt":{"bundle_id":42}}`, "bundle_id"},
+	{"object for array", `{"status":0,"latest_receipt_info":{"product_id":"monthly"}}`, "latest_receipt_info"},
+	{"number for quoted milliseconds", `{"status":0,"latest_receipt_info":[{"expires_date_ms":1600000000000}]}`, "expires_date_ms"},
+	{"invalid flag", `{"status":0,"latest_receipt_info":[{"is_trial_period":"maybe"}]}`, ""},
+	{"null status with receipt", `{"status":null,"latest_receipt_info":[{"product_id":"monthly","expires_date_ms":"99999999999999"}]}`, ""},
+	{"trailing garbage", `{"status":0} garbage`, ""},
+	{"second object", `{"status":0}{"status":21003}`, ""},
+}
+
+func TestParseResponseMalformedPayloads(t *testing.T) {
+	for _, parser := range parsers {
+		for _, tt := range malformedPayloads {
+			t.Run(parser.name+"/"+tt.name, func(t *testing.T) {
+				resp, err := parser.parse([]byte(tt.body))
+				var decodeErr *DecodeError
+				if !errors.As(err, &decodeErr) {
+					t.Fatalf("want a decode error, got response %+v and error %v", resp, err)
+				}
+				if tt.wantField != "" && !strings.Contains(decodeErr.Field, tt.wantField) {
+					t.Fatalf("want the error to name field %s, got %q", tt.wantField, decodeErr.Field)
+				}
+			})
+		}
+	}
+}
+
+func TestParseResponseToleratesMissingArrays(t *testing.T) {
+	for _, body := range []string{
+		`{"status":0}`,
+		`{"status":0,"receipt":{}}`,
+		`{"status":0,"latest_receipt_info":null,"pending_renewal_info":null,"receipt":{"in_app":null}}`,
+		`{"status":21004}`,
+	} {
+		for _, parser := range parsers {
+			resp, err := parser.parse([]byte(body))
+			if err != nil {
+				t.Fatalf("want %s parsed by %s, got %v", body, parser.name, err)
+			}
+			if len(resp.LatestReceiptInfo) != 0 || len(resp.Receipt.InApp) != 0 || len(resp.Subscriptions()) != 0 {
+				t.Fatalf("want no transactions in %s parsed by %s, got %+v", body, parser.name, resp)
+			}
+		}
+	}
+}
+
+func TestResponsePersisterReceivesRejectedResponses(t *testing.T) {
+	const body = `{"status":21003}`
+	store := newStubStore(t, stubResponse{body: body})
+	var persisted []string
+	c := store.client().WithResponsePersister(func(ctx context.Context, requestKey string, raw []byte) {
+		if requestKey != FingerprintReceipt("receipt") {
+			t.Errorf("want the receipt fingerprint as request key, got %s", requestKey)
+		}
+		persisted = append(persisted, string(raw))
+	})
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if result.OK() {
+		t.Fatal("want the 21003 response rejected")
+	}
+	if len(persisted) != 1 || persisted[0] != body {
+		t.Fatalf("want the rejected response persisted, got %q", persisted)
+	}
+}
+
+func TestWithEnvironmentOrderIgnoresEnvironmentsWithoutEndpoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []Environment
+		want  Environment
+	}{
+		{"xcode first", []Environment{EnvironmentXcode, EnvironmentSandbox}, EnvironmentSandbox},
+		{"unknown first", []Environment{"Staging", EnvironmentSandbox, EnvironmentProduction}, EnvironmentSandbox},
+		{"xcode only keeps previous order", []Environment{EnvironmentXcode}, EnvironmentProduction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStubStore(t, stubResponse{body: `{"status":0}`})
+			c := store.client().WithEnvironmentOrder(tt.order...)
+
+			result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+			if result.Environment != tt.want || store.requests[0].env != tt.want {
+				t.Fatalf("want the request sent to %s, got %s", tt.want, store.requests[0].env)
+			}
+		})
+	}
+}
+
+func TestAppContextSkipsResponsesWithErrorStatus(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":21010}`})
+	c := store.client().WithAppContext("com.example.app", "123")
+
+	result := c.VerifyWithResult(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if !errors.Is(result.Err, ErrReceiptUnauthorized) || errors.Is(result.Err, ErrBundleIDMismatch) {
+		t.Fatalf("want the 21010 status reported, got %v", result.Err)
+	}
+	if result.Response == nil {
+		t.Fatal("want the response returned")
+	}
+}
+
+func TestAppContextRejectsReceiptOfAnotherApp(t *testing.T) {
+	store := newStubStore(t, stubResponse{body: `{"status":0,"receipt":{"bundle_id":"com.example.other"}}`})
+	c := store.client().WithAppContext("com.example.app", "")
+
+	_, resp, err := c.Verify(context.Background(), &ReceiptRequest{ReceiptData: "receipt"})
+
+	if !errors.Is(err, ErrBundleIDMismatch) || resp == nil {
+		t.Fatalf("want a bundle id mismatch along with the response, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package storekit
+
+import "time"
+
+// Clock tells the current time and waits for durations to pass. Replace it to
+// make time-dependent behavior such as retry backoff deterministic.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,79 @@
+package storekit
+
+import "context"
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	metadataKey
+	cacheBypassKey
+)
+
+// ContextWithRequestID returns a copy of the context carrying the correlation
+// identifier of a verification. Hooks such as the response persister receive
+// the context and can read it back with RequestIDFromContext.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the correlation identifier carried by the
+// context, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
+
+// ContextWithMetadata returns a copy of the context carrying the given
+// key-value pairs, such as a user ID or platform, on top of the ones it
+// already carries. Hooks receive the context and can read them back with
+// MetadataFromContext.
+func ContextWithMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	merged := MetadataFromContext(ctx)
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metadataKey, merged)
+}
+
+// MetadataFromContext returns a copy of the key-value pairs carried by the
+// context. The map is empty, not nil, when there are none.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey).(map[string]string)
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+// contextWithoutMetadata returns a copy of the context whose metadata omits the
+// given keys.
+func contextWithoutMetadata(ctx context.Context, keys []string) context.Context {
+	metadata, ok := ctx.Value(metadataKey).(map[string]string)
+	if !ok {
+		return ctx
+	}
+
+	redacted := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if !containsString(keys, k) {
+			redacted[k] = v
+		}
+	}
+	return context.WithValue(ctx, metadataKey, redacted)
+}
+
+// ContextWithCacheBypass returns a copy of the context that makes
+// VerifyEntitlement call the App Store even when the entitlement cache holds a
+// valid entry, for instance to refresh the entitlement on a notification.
+func ContextWithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey, true)
+}
+
+// cacheBypassed reports whether the context asks to bypass the entitlement
+// cache.
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey).(bool)
+	return bypass
+}
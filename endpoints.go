@@ -0,0 +1,59 @@
+package storekit
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// EndpointConfig overrides the URLs of the App Store endpoints, for instance to
+// route the traffic through a fixed egress proxy or a test server. Empty URLs
+// keep the Apple default.
+type EndpointConfig struct {
+	// The verifyReceipt URL of the sandbox environment.
+	SandboxVerifyURL string
+
+	// The verifyReceipt URL of the production environment.
+	ProductionVerifyURL string
+}
+
+// Validate reports the first URL of the configuration that is not an absolute
+// http or https URL.
+func (cfg EndpointConfig) Validate() error {
+	for _, endpoint := range []struct{ name, value string }{
+		{"sandbox verify", cfg.SandboxVerifyURL},
+		{"production verify", cfg.ProductionVerifyURL},
+	} {
+		if endpoint.value == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint.value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s url", endpoint.name)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid %s url %q: want an absolute http or https url", endpoint.name, endpoint.value)
+		}
+	}
+	return nil
+}
+
+// hasVerificationEndpoint reports whether the App Store verifies receipts
+// against the environment.
+func hasVerificationEndpoint(env Environment) bool {
+	return env == EnvironmentSandbox || env == EnvironmentProduction
+}
+
+// verificationURL returns the verifyReceipt URL of the environment.
+func (cfg EndpointConfig) verificationURL(env Environment) string {
+	if env == EnvironmentSandbox {
+		if cfg.SandboxVerifyURL != "" {
+			return cfg.SandboxVerifyURL
+		}
+		return sandboxReceiptVerificationURL
+	}
+	if cfg.ProductionVerifyURL != "" {
+		return cfg.ProductionVerifyURL
+	}
+	return productionReceiptVerificationURL
+}
@@ -0,0 +1,50 @@
+package storekit
+
+// EnrichedTransaction is a transaction joined with the pending renewal
+// information that pertains to it.
+type EnrichedTransaction struct {
+	InAppPurchaseReceipt
+
+	// The pending renewal info of the subscription, set on the latest
+	// transaction of each subscription only, since it describes the renewal of
+	// the current period. Nil for earlier periods and other products.
+	RenewalInfo *PendingRenewalInfo
+
+	// Whether the App Store is attempting to renew the subscription after this
+	// transaction expired.
+	IsInBillingRetryPeriod bool
+
+	// Whether the subscription renews automatically after this transaction.
+	AutoRenewEnabled bool
+}
+
+// EnrichedTransactions returns the transactions of both the latest receipt info
+// and the receipt, without duplicates and ordered by purchase date, each
+// joined with the pending renewal info that pertains to it.
+func (r *ReceiptResponse) EnrichedTransactions() []EnrichedTransaction {
+	txs := r.sortedTransactions()
+	latest := make(map[string]InAppPurchaseReceipt)
+	for _, tx := range txs {
+		if tx.ExpiresDateMs == 0 {
+			continue
+		}
+		if current, ok := latest[tx.OriginalTransactionId]; !ok || isLater(tx, current) {
+			latest[tx.OriginalTransactionId] = tx
+		}
+	}
+
+	enriched := make([]EnrichedTransaction, 0, len(txs))
+	for _, tx := range txs {
+		e := EnrichedTransaction{InAppPurchaseReceipt: tx}
+		if current, ok := latest[tx.OriginalTransactionId]; ok && current.TransactionId == tx.TransactionId {
+			if info, ok := renewalInfoOf(r.PendingRenewalInfo, tx); ok {
+				e.RenewalInfo = &info
+				e.IsInBillingRetryPeriod = info.IsInBillingRetry()
+				e.AutoRenewEnabled = info.AutoRenewStatus.Bool()
+			}
+		}
+		enriched = append(enriched, e)
+	}
+
+	return enriched
+}
@@ -0,0 +1,231 @@
+package storekit
+
+import "time"
+
+// EntitlementState is the state of the access granted by a product.
+type EntitlementState string
+
+const (
+	// The product grants access at the evaluated time.
+	EntitlementStateActive EntitlementState = "active"
+
+	// The subscription period ended at or before the evaluated time: a
+	// subscription is active up to, but not including, its expiration time.
+	EntitlementStateExpired EntitlementState = "expired"
+
+	// Apple customer support refunded the transaction, or the subscription was
+	// upgraded to another product.
+	EntitlementStateRefunded EntitlementState = "refunded"
+
+	// Access to a family-shared purchase was revoked, for instance because the
+	// purchaser stopped sharing it or left the family group.
+	EntitlementStateRevoked EntitlementState = "revoked"
+
+	// The purchase awaits approval from a family organizer through Ask to Buy.
+	EntitlementStatePending EntitlementState = "pending"
+)
+
+// Entitlement describes the access granted by the latest transaction of a
+// product.
+type Entitlement struct {
+	// The unique identifier of the product purchased.
+	ProductId string
+
+	// The transaction identifier of the original purchase.
+	OriginalTransactionId string
+
+	// The identifier of the transaction the entitlement is derived from.
+	TransactionId string
+
+	// The time the subscription expires or renews. Zero for products that do not
+	// expire.
+	ExpiresAt time.Time
+
+	// Whether the access comes from a purchase shared through Family Sharing.
+	FamilyShared bool
+
+	// The state of the entitlement at the evaluated time.
+	State EntitlementState
+}
+
+// IsActive reports whether the entitlement grants access.
+func (e *Entitlement) IsActive() bool {
+	return e.State == EntitlementStateActive
+}
+
+// Entitlement returns the entitlement of the given product at the given time
+// based on the latest receipt info of the response, or on the in-app purchases
+// of the receipt when the response has no latest receipt info. It returns
+// false when the response holds no transaction for the product.
+//
+// A response with the 21006 status is a valid receipt of an expired
+// subscription, so its entitlement is never active.
+func (r *ReceiptResponse) Entitlement(productID string, at time.Time) (*Entitlement, bool) {
+	e, ok := entitlementOf(productID, at, r.transactions())
+	if ok && e.IsActive() && r.Status == ReceiptResponseStatusValidButSubscriptionExpired {
+		e.State = EntitlementStateExpired
+	}
+
+	return e, ok
+}
+
+// askToBuyExpiry is how long a family organizer has to approve an Ask to Buy
+// request before it expires.
+const askToBuyExpiry = 24 * time.Hour
+
+// DeferredEntitlement returns the entitlement of the given product at the given
+// time for a purchase that StoreKit reported as deferred at deferredAt, which
+// happens when a family member with Ask to Buy enabled requests it.
+//
+// Neither the verifyReceipt response nor the notifications carry the deferred
+// state: a deferred purchase has no transaction until it is approved. So the
+// purchase is pending when the response holds no transaction of the product
+// purchased at or after deferredAt, unless an earlier transaction of the
+// product still grants access. The request expires when the organizer does
+// not act on it within 24 hours, and a declined request is never reported, so
+// past that the purchase is no longer pending and the regular entitlement
+// applies. It returns false when the purchase is neither pending nor backed by
+// a transaction.
+func (r *ReceiptResponse) DeferredEntitlement(productID string, deferredAt, at time.Time) (*Entitlement, bool) {
+	e, ok := r.Entitlement(productID, at)
+	if ok && e.IsActive() {
+		return e, true
+	}
+	if tx, found := latestTransaction(r.transactions(), productID); found && !timeFromMs(tx.PurchaseDateMs).Before(deferredAt) {
+		return e, ok
+	}
+	if !at.Before(deferredAt.Add(askToBuyExpiry)) {
+		return e, ok
+	}
+
+	return &Entitlement{
+		ProductId: productID,
+		State:     EntitlementStatePending,
+	}, true
+}
+
+// IsRevoked reports whether access to the family-shared purchase of the given
+// product was revoked.
+func (r *ReceiptResponse) IsRevoked(productID string, at time.Time) bool {
+	e, ok := r.Entitlement(productID, at)
+	return ok && e.State == EntitlementStateRevoked
+}
+
+// IsSubscriptionActive reports whether the subscription to the given product
+// grants access at the given time.
+func (r *ReceiptResponse) IsSubscriptionActive(productID string, at time.Time) bool {
+	e, ok := r.Entitlement(productID, at)
+	return ok && e.IsActive()
+}
+
+// EntitlementMap reports for each of the given products whether it grants
+// access at the given time, as IsSubscriptionActive does. Products without a
+// transaction in the response are reported as not entitled. When
+// includeGracePeriod is set, an expired subscription still in its billing
+// grace period counts as entitled, as Apple recommends while the App Store
+// attempts to renew it.
+func (r *ReceiptResponse) EntitlementMap(productIDs []string, at time.Time, includeGracePeriod bool) map[string]bool {
+	entitled := make(map[string]bool, len(productIDs))
+	for _, productID := range productIDs {
+		active := r.IsSubscriptionActive(productID, at)
+		if !active && includeGracePeriod {
+			active = r.inGracePeriod(productID, at)
+		}
+		entitled[productID] = active
+	}
+	return entitled
+}
+
+// inGracePeriod reports whether the subscription to the given product expired
+// but its billing grace period covers the given time.
+func (r *ReceiptResponse) inGracePeriod(productID string, at time.Time) bool {
+	e, ok := r.Entitlement(productID, at)
+	if !ok || e.State != EntitlementStateExpired {
+		return false
+	}
+	graceEnd, ok := r.GracePeriodEnd(productID)
+	return ok && at.Before(graceEnd)
+}
+
+// transactions returns the transactions the entitlement helpers work on: the
+// latest receipt info, or the in-app purchases of the receipt when the App
+// Store returned no latest receipt info, as it does for some older and
+// non-renewing receipts.
+func (r *ReceiptResponse) transactions() []InAppPurchaseReceipt {
+	if len(r.LatestReceiptInfo) == 0 {
+		return r.Receipt.InApp
+	}
+	return fromLatestReceiptInfo(r.LatestReceiptInfo)
+}
+
+func entitlementOf(productID string, at time.Time, txs []InAppPurchaseReceipt) (*Entitlement, bool) {
+	tx, ok := latestTransaction(txs, productID)
+	if !ok {
+		return nil, false
+	}
+
+	e := &Entitlement{
+		ProductId:             tx.ProductId,
+		OriginalTransactionId: tx.OriginalTransactionId,
+		TransactionId:         tx.TransactionId,
+		ExpiresAt:             timeFromMs(tx.ExpiresDateMs),
+		FamilyShared:          tx.IsFamilyShared(),
+	}
+
+	switch {
+	case tx.CancellationDateMs != 0 && tx.IsFamilyShared():
+		e.State = EntitlementStateRevoked
+	case tx.CancellationDateMs != 0:
+		e.State = EntitlementStateRefunded
+	case !e.ExpiresAt.IsZero() && !at.Before(e.ExpiresAt):
+		e.State = EntitlementStateExpired
+	default:
+		e.State = EntitlementStateActive
+	}
+
+	return e, true
+}
+
+// latestTransaction returns the transaction of the given product that expires
+// last, or was purchased last for products that do not expire.
+func latestTransaction(txs []InAppPurchaseReceipt, productID string) (InAppPurchaseReceipt, bool) {
+	var latest InAppPurchaseReceipt
+	found := false
+	for _, tx := range txs {
+		if tx.ProductId != productID {
+			continue
+		}
+		if !found || isLater(tx, latest) {
+			latest = tx
+			found = true
+		}
+	}
+
+	return latest, found
+}
+
+// isLater reports whether a ends after b.
+func isLater(a, b InAppPurchaseReceipt) bool {
+	if a.ExpiresDateMs != b.ExpiresDateMs {
+		return a.ExpiresDateMs > b.ExpiresDateMs
+	}
+	return a.PurchaseDateMs > b.PurchaseDateMs
+}
+
+// fromLatestReceiptInfo converts latest receipt info entries to the in-app
+// purchase receipt type they mirror field by field.
+func fromLatestReceiptInfo(infos []LatestReceiptInfo) []InAppPurchaseReceipt {
+	txs := make([]InAppPurchaseReceipt, 0, len(infos))
+	for _, info := range infos {
+		txs = append(txs, InAppPurchaseReceipt(info))
+	}
+	return txs
+}
+
+// timeFromMs converts UNIX epoch milliseconds to time. Zero stays zero.
+func timeFromMs(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
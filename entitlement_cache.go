@@ -0,0 +1,54 @@
+package storekit
+
+import "context"
+
+// EntitlementCache stores entitlements between verifications. The key
+// identifies the receipt data and the product without carrying the receipt
+// itself. Implementations must be safe for concurrent use.
+type EntitlementCache interface {
+	Get(ctx context.Context, key string) (*Entitlement, bool)
+	Set(ctx context.Context, key string, entitlement *Entitlement)
+}
+
+// VerifyEntitlement returns the entitlement of the given product granted by the
+// receipt, as of the time of the client clock. With an entitlement cache set,
+// an active subscription entitlement is served from the cache until the cache
+// margin before it expires, and the App Store is only called past that or when
+// the context was made with ContextWithCacheBypass. Entitlements of products
+// that do not expire are never cached, as nothing tells when they change.
+//
+// Verification failures and responses with a non-zero status are reported as
+// in VerifyWithResult. The entitlement is nil when the receipt holds no
+// transaction for the product.
+func (c *client) VerifyEntitlement(ctx context.Context, receiptRequest *ReceiptRequest, productID string) (*Entitlement, error) {
+	key := FingerprintReceipt(receiptRequest.ReceiptData) + ":" + productID
+	if c.entitlementCache != nil && !cacheBypassed(ctx) {
+		if e, ok := c.entitlementCache.Get(ctx, key); ok && c.isEntitlementFresh(e) {
+			return e, nil
+		}
+	}
+
+	result := c.VerifyWithResult(ctx, receiptRequest)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	e, ok := result.Response.Entitlement(productID, c.clock.Now())
+	if !ok {
+		return nil, nil
+	}
+	if c.entitlementCache != nil && c.isEntitlementFresh(e) {
+		c.entitlementCache.Set(ctx, key, e)
+	}
+
+	return e, nil
+}
+
+// isEntitlementFresh reports whether the cached entitlement can be served
+// without calling the App Store.
+func (c *client) isEntitlementFresh(e *Entitlement) bool {
+	if !e.IsActive() || e.ExpiresAt.IsZero() {
+		return false
+	}
+	return c.clock.Now().Before(e.ExpiresAt.Add(-c.entitlementCacheMargin))
+}
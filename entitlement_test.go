@@ -0,0 +1,113 @@
+package storekit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// receiptWithoutLatestInfo is a response to a non-renewing receipt: it carries
+// a latest receipt but no latest receipt info, so the transactions live in the
+// in-app purchases of the receipt only.
+const receiptWithoutLatestInfo = `{
+	"status": 0,
+	"environment": "Production",
+	"latest_receipt": "bGF0ZXN0",
+	"receipt": {
+		"bundle_id": "com.example.app",
+		"in_app": [
+			{
+				"product_id": "season_pass",
+				"transaction_id": "1000",
+				"original_transaction_id": "1000",
+				"purchase_date_ms": "1600000000000",
+				"expires_date_ms": "1610000000000"
+			},
+			{
+				"product_id": "lifetime",
+				"transaction_id": "1001",
+				"original_transaction_id": "1001",
+				"purchase_date_ms": "1600000000000"
+			}
+		]
+	}
+}`
+
+func decodeResponse(t *testing.T, body string) *ReceiptResponse {
+	t.Helper()
+	resp, err := parseResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("could not parse fixture: %v", err)
+	}
+	return resp
+}
+
+func TestEntitlementFallsBackToReceiptInApp(t *testing.T) {
+	resp := decodeResponse(t, receiptWithoutLatestInfo)
+	if _, ok := resp.LatestReceiptData(); !ok {
+		t.Fatal("want the latest receipt of the fixture")
+	}
+	at := time.Unix(1605000000, 0)
+
+	e, ok := resp.Entitlement("season_pass", at)
+	if !ok || !e.IsActive() || e.TransactionId != "1000" {
+		t.Fatalf("want the season pass active from the receipt, got %+v", e)
+	}
+	if !resp.IsSubscriptionActive("lifetime", at) {
+		t.Fatal("want the non-expiring product active")
+	}
+	if _, ok := resp.Entitlement("unknown", at); ok {
+		t.Fatal("want no entitlement for a product without transactions")
+	}
+}
+
+func TestEntitlementPrefersLatestReceiptInfo(t *testing.T) {
+	var resp ReceiptResponse
+	err := json.Unmarshal([]byte(`{
+		"status": 0,
+		"latest_receipt_info": [{"product_id": "monthly", "transaction_id": "2", "expires_date_ms": "1610000000000"}],
+		"receipt": {"in_app": [{"product_id": "monthly", "transaction_id": "1", "expires_date_ms": "1600000000000"}]}
+	}`), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := resp.Entitlement("monthly", time.Unix(1605000000, 0))
+	if !ok || e.TransactionId != "2" || !e.IsActive() {
+		t.Fatalf("want the latest receipt info transaction, got %+v", e)
+	}
+}
+
+func TestEntitlementExpiryBoundary(t *testing.T) {
+	expiresAt := time.Unix(1610000000, 0)
+	resp := &ReceiptResponse{LatestReceiptInfo: []LatestReceiptInfo{{
+		ProductId:      "monthly",
+		TransactionId:  "1",
+		PurchaseDateMs: 1600000000000,
+		ExpiresDateMs:  expiresAt.UnixNano() / int64(time.Millisecond),
+	}}}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want EntitlementState
+	}{
+		{"just before", expiresAt.Add(-time.Millisecond), EntitlementStateActive},
+		{"at", expiresAt, EntitlementStateExpired},
+		{"just after", expiresAt.Add(time.Millisecond), EntitlementStateExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := resp.Entitlement("monthly", tt.at)
+			if !ok || e.State != tt.want {
+				t.Fatalf("want %s, got %+v", tt.want, e)
+			}
+			if got := resp.IsSubscriptionActive("monthly", tt.at); got != (tt.want == EntitlementStateActive) {
+				t.Fatalf("want IsSubscriptionActive %v, got %v", tt.want == EntitlementStateActive, got)
+			}
+			if _, ok := resp.RemainingTrialPeriod("monthly", tt.at); ok {
+				t.Fatal("want no trial period for a regular transaction")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package storekit
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Environment is the App Store environment a receipt is verified against.
+type Environment string
+
+const (
+	// The sandbox environment, used for development, TestFlight and App Review
+	// purchases.
+	EnvironmentSandbox Environment = "Sandbox"
+
+	// The production environment, used for purchases made on the App Store.
+	EnvironmentProduction Environment = "Production"
+
+	// The local StoreKit testing environment of Xcode, used with a StoreKit
+	// configuration file. The App Store never verifies receipts against it; it
+	// only shows up as the environment of receipts signed locally by Xcode.
+	EnvironmentXcode Environment = "Xcode"
+)
+
+// ParseEnvironment returns the environment named by the given value as it
+// appears in any App Store data: the environment of verifyReceipt responses and
+// notifications, whatever its casing, the PROD abbreviation of version 1
+// notifications, or the host name of a verifyReceipt or App Store Server API
+// endpoint.
+func ParseEnvironment(value string) (Environment, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "sandbox", "sandbox.itunes.apple.com", "api.storekit-sandbox.itunes.apple.com":
+		return EnvironmentSandbox, nil
+	case "production", "prod", "buy.itunes.apple.com", "api.storekit.itunes.apple.com":
+		return EnvironmentProduction, nil
+	case "xcode":
+		return EnvironmentXcode, nil
+	default:
+		return "", errors.Errorf("unknown app store environment %q", value)
+	}
+}
+
+func (e Environment) String() string {
+	return string(e)
+}
+
+// UnmarshalJSON normalizes the environment with ParseEnvironment. Unknown values
+// are kept as is, so that new environments do not fail decoding.
+func (e *Environment) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	env, err := ParseEnvironment(value)
+	if err != nil {
+		env = Environment(value)
+	}
+	*e = env
+	return nil
+}
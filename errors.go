@@ -0,0 +1,140 @@
+package storekit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSandboxReceiptRejected is returned by a production client set to reject
+// sandbox receipts when the App Store reports a sandbox receipt.
+var ErrSandboxReceiptRejected = errors.New("sandbox receipt rejected in production")
+
+// ErrXcodeReceiptRejected is returned when the App Store reports a receipt
+// generated by StoreKit testing in Xcode and the client does not allow them.
+var ErrXcodeReceiptRejected = errors.New("xcode receipt rejected")
+
+// ErrBundleIDMismatch is returned when the receipt belongs to an app with an
+// unexpected bundle identifier.
+var ErrBundleIDMismatch = errors.New("receipt bundle id mismatch")
+
+// ErrAppAppleIDMismatch is returned when the receipt belongs to an app with an
+// unexpected Apple ID.
+var ErrAppAppleIDMismatch = errors.New("receipt app apple id mismatch")
+
+// ErrReceiptUnauthorized matches, through errors.Is, a *StatusError for the
+// 21010 status: the receipt could not be authorized. Treat it as if no
+// purchase was ever made and deny or revoke access; retrying does not help.
+var ErrReceiptUnauthorized = errors.New("receipt could not be authorized")
+
+// ErrNotificationPasswordMismatch is returned when the password of a version 1
+// notification does not match the expected shared secret.
+var ErrNotificationPasswordMismatch = errors.New("notification password mismatch")
+
+// StatusError reports a non-zero status returned by the App Store.
+type StatusError struct {
+	Status ReceiptResponseStatus
+
+	// Local diagnostics of the receipt data sent. Only set for the 21003 status,
+	// to help tell client-side corruption from tampering.
+	Diagnostics *ReceiptDiagnostics
+}
+
+// ReceiptDiagnostics describes the receipt data of a request as seen locally.
+type ReceiptDiagnostics struct {
+	// Whether the receipt data is valid base64. Data that is not most likely got
+	// corrupted on its way from the device.
+	Base64Valid bool
+
+	// The length in bytes of the decoded receipt. Zero when the receipt data is
+	// not valid base64.
+	DecodedLength int
+}
+
+func newStatusError(status ReceiptResponseStatus, receiptRequest *ReceiptRequest) *StatusError {
+	e := &StatusError{Status: status}
+	if status == ReceiptResponseStatusNotAuthenticated {
+		e.Diagnostics = &ReceiptDiagnostics{}
+		receipt, err := base64.StdEncoding.DecodeString(receiptRequest.ReceiptData)
+		if err == nil {
+			e.Diagnostics.Base64Valid = true
+			e.Diagnostics.DecodedLength = len(receipt)
+		}
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	return "receipt rejected by app store with status " + strconv.Itoa(int(e.Status))
+}
+
+// Is reports whether the status error matches the target sentinel error.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrReceiptUnauthorized && e.Status == ReceiptResponseStatusCouldNotBeAuthorized
+}
+
+// HTTPError reports an unexpected HTTP status returned by the App Store.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return "app store http error (" + e.Status + ")"
+}
+
+// MissingExpiryError reports a transaction of a known subscription product that
+// has no expiration date.
+type MissingExpiryError struct {
+	ProductId     string
+	TransactionId string
+}
+
+func (e *MissingExpiryError) Error() string {
+	return "subscription transaction " + e.TransactionId + " of product " + e.ProductId + " has no expiration date"
+}
+
+// ResponseSkewError reports a response whose request date is too far from the
+// local time.
+type ResponseSkewError struct {
+	// The time the App Store reports processing the request at.
+	RequestedAt time.Time
+
+	// The time of the client clock when the response was checked.
+	LocalTime time.Time
+}
+
+func (e *ResponseSkewError) Error() string {
+	return "app store request date " + e.RequestedAt.UTC().Format(time.RFC3339) + " is " + e.LocalTime.Sub(e.RequestedAt).String() + " away from local time"
+}
+
+// DecodeError reports an App Store response that does not have the expected
+// shape, such as a number where a string is expected or a missing status.
+type DecodeError struct {
+	// The JSON path of the offending field, when known.
+	Field string
+
+	Err error
+}
+
+func newDecodeError(err error) *DecodeError {
+	e := &DecodeError{Err: err}
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		e.Field = typeErr.Field
+	}
+	return e
+}
+
+func (e *DecodeError) Error() string {
+	if e.Field != "" {
+		return "could not unmarshal app store response field " + e.Field + ": " + e.Err.Error()
+	}
+	return "could not unmarshal app store response: " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
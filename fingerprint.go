@@ -0,0 +1,43 @@
+package storekit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// FingerprintReceipt returns a stable key for the given base64 encoded receipt
+// data, suited to deduplication stores and idempotency keys. Encoding quirks
+// do not change it: whitespace and line breaks are dropped, the URL-safe
+// alphabet is mapped to the standard one and missing padding is restored
+// before the data is hashed with SHA-256. Clean standard base64 data thus
+// hashes as is.
+//
+// The fingerprint is a content hash, not a signature: it tells identical
+// receipts apart from different ones but says nothing about authenticity.
+func FingerprintReceipt(receiptData string) string {
+	sum := sha256.Sum256([]byte(normalizeReceiptData(receiptData)))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeReceiptData rewrites base64 receipt data in the padded standard
+// encoding.
+func normalizeReceiptData(receiptData string) string {
+	var b strings.Builder
+	b.Grow(len(receiptData) + 3)
+	for _, r := range receiptData {
+		switch r {
+		case ' ', '\t', '\n', '\r':
+		case '-':
+			b.WriteByte('+')
+		case '_':
+			b.WriteByte('/')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	for b.Len()%4 != 0 {
+		b.WriteByte('=')
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package storekit
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// FlexBool is a boolean the App Store encodes inconsistently: as a JSON
+// boolean, as a quoted "true" or "false", or as "1" and "0". It decodes from
+// any of these and encodes as a quoted string, like most App Store payloads.
+type FlexBool bool
+
+// Bool returns the value as a plain boolean.
+func (b FlexBool) Bool() bool {
+	return bool(b)
+}
+
+func (b FlexBool) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatBool(bool(b)))), nil
+}
+
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	v, err := parseFlexBool(data)
+	if err != nil {
+		return err
+	}
+	*b = FlexBool(v)
+	return nil
+}
+
+// parseFlexBool decodes a JSON boolean that may be quoted or numeric. Null and
+// the empty string decode as false.
+func parseFlexBool(data []byte) (bool, error) {
+	s := string(bytes.Trim(data, `"`))
+	switch s {
+	case "true", "1":
+		return true, nil
+	case "false", "0", "", "null":
+		return false, nil
+	default:
+		return false, errors.Errorf("invalid boolean %s", data)
+	}
+}
@@ -0,0 +1,53 @@
+package storekit
+
+import "time"
+
+// InactiveReason is why a product grants no access.
+type InactiveReason string
+
+const (
+	// The subscription period ended, or the response has the 21006 status.
+	InactiveReasonExpired InactiveReason = "expired"
+
+	// Apple customer support refunded the transaction, or the subscription was
+	// upgraded to another product.
+	InactiveReasonRefunded InactiveReason = "refunded"
+
+	// Access to the family-shared purchase was revoked.
+	InactiveReasonRevoked InactiveReason = "revoked"
+
+	// The response holds no transaction for the product.
+	InactiveReasonNeverSubscribed InactiveReason = "never_subscribed"
+
+	// The purchase awaits approval through Ask to Buy. Only reported for
+	// entitlements returned by DeferredEntitlement, as the receipt alone does
+	// not tell that a purchase was requested.
+	InactiveReasonPendingApproval InactiveReason = "pending_approval"
+)
+
+// InactiveReason returns why the given product grants no access at the given
+// time. It returns false when the product is active.
+func (r *ReceiptResponse) InactiveReason(productID string, at time.Time) (InactiveReason, bool) {
+	e, ok := r.Entitlement(productID, at)
+	if !ok {
+		return InactiveReasonNeverSubscribed, true
+	}
+	return e.InactiveReason()
+}
+
+// InactiveReason returns why the entitlement grants no access. It returns false
+// when the entitlement is active.
+func (e *Entitlement) InactiveReason() (InactiveReason, bool) {
+	switch e.State {
+	case EntitlementStateExpired:
+		return InactiveReasonExpired, true
+	case EntitlementStateRefunded:
+		return InactiveReasonRefunded, true
+	case EntitlementStateRevoked:
+		return InactiveReasonRevoked, true
+	case EntitlementStatePending:
+		return InactiveReasonPendingApproval, true
+	default:
+		return "", false
+	}
+}
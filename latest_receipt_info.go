@@ -67,14 +67,14 @@ type LatestReceiptInfo struct {
 
 	// An indicator of whether an auto-renewable subscription is in the introductory
 	// price period.
-	IsInIntroOfferPeriod string `json:"is_in_intro_offer_period,omitempty"`
+	IsInIntroOfferPeriod FlexBool `json:"is_in_intro_offer_period,omitempty"`
 
 	// An indicator of whether a subscription is in the free trial period.
-	IsTrialPeriod string `json:"is_trial_period,omitempty"`
+	IsTrialPeriod FlexBool `json:"is_trial_period,omitempty"`
 
 	// An indicator that a subscription has been canceled due to an upgrade. This
 	// field is only present for upgrade transactions.
-	IsUpgraded string `json:"is_upgraded,omitempty"`
+	IsUpgraded FlexBool `json:"is_upgraded,omitempty"`
 
 	// The reference name of a subscription offer that you configured in App Store
 	// Connect. This field is present when a customer redeemed a subscription offer
@@ -147,3 +147,43 @@ type LatestReceiptInfo struct {
 	// subscription purchases.
 	WebOrderLineItemId string `json:"web_order_line_item_id,omitempty"`
 }
+
+// IsFamilyShared reports whether the transaction belongs to a family member who
+// benefits from a purchase shared through Family Sharing.
+func (r *LatestReceiptInfo) IsFamilyShared() bool {
+	return r.InAppOwnershipType == InAppOwnershipTypeFamilyShared
+}
+
+// WebOrderLineItem returns the identifier of the subscription billing period
+// the transaction pays for. Unlike the transaction identifier, it stays the
+// same across restores and resends of the same period, which makes it a
+// suitable idempotency key for revenue events. It returns false for
+// transactions that are not auto-renewable subscription periods.
+func (r *LatestReceiptInfo) WebOrderLineItem() (id string, ok bool) {
+	return r.WebOrderLineItemId, r.WebOrderLineItemId != ""
+}
+
+// OfferCode returns the reference name of the offer code redeemed for the
+// transaction. It returns false when no offer code was redeemed.
+func (r *LatestReceiptInfo) OfferCode() (refName string, ok bool) {
+	return r.OfferCodeRefName, r.OfferCodeRefName != ""
+}
+
+// IsRefunded reports whether Apple customer support refunded the transaction.
+// The cancellation date is also set on a subscription period cut short by an
+// upgrade, which is not a refund.
+func (r *LatestReceiptInfo) IsRefunded() bool {
+	return r.CancellationDateMs != 0 && !r.IsUpgraded.Bool()
+}
+
+// RefundReason returns why the transaction was refunded. It returns false when
+// the transaction was not refunded, including when it was upgraded.
+func (r *LatestReceiptInfo) RefundReason() (CancellationReason, bool) {
+	return CancellationReason(r.CancellationReason), r.IsRefunded()
+}
+
+// PromotionalOffer returns the identifier of the promotional offer redeemed for
+// the transaction. It returns false when no promotional offer applies.
+func (r *LatestReceiptInfo) PromotionalOffer() (offerID string, ok bool) {
+	return r.PromotionalOfferId, r.PromotionalOfferId != ""
+}
@@ -0,0 +1,92 @@
+package storekit
+
+// MergeResponses merges a freshly verified response into a stored one, keeping
+// the history the App Store dropped from the fresh response, for instance when
+// it was verified excluding old transactions.
+//
+// Transactions of both the latest receipt info and the receipt are united by
+// web order line item identifier, or by transaction identifier for
+// transactions without one, and the fresh entry wins on conflicts. Pending
+// renewal info is united by original transaction identifier the same way.
+// Every other field comes from the fresh response. Neither argument is
+// modified; a nil argument yields the other one.
+func MergeResponses(stored, fresh *ReceiptResponse) *ReceiptResponse {
+	if stored == nil {
+		return fresh
+	}
+	if fresh == nil {
+		return stored
+	}
+
+	merged := *fresh
+	merged.LatestReceiptInfo = mergeLatestReceiptInfo(stored.LatestReceiptInfo, fresh.LatestReceiptInfo)
+	merged.Receipt.InApp = mergeTransactions(stored.Receipt.InApp, fresh.Receipt.InApp)
+	merged.PendingRenewalInfo = mergeRenewalInfo(stored.PendingRenewalInfo, fresh.PendingRenewalInfo)
+
+	return &merged
+}
+
+func mergeLatestReceiptInfo(stored, fresh []LatestReceiptInfo) []LatestReceiptInfo {
+	txs := mergeTransactions(fromLatestReceiptInfo(stored), fromLatestReceiptInfo(fresh))
+	if txs == nil {
+		return nil
+	}
+
+	infos := make([]LatestReceiptInfo, 0, len(txs))
+	for _, tx := range txs {
+		infos = append(infos, LatestReceiptInfo(tx))
+	}
+	return infos
+}
+
+// mergeTransactions unites the transactions by merge key, preferring fresh
+// entries, and orders the result by purchase date.
+func mergeTransactions(stored, fresh []InAppPurchaseReceipt) []InAppPurchaseReceipt {
+	if len(stored) == 0 && len(fresh) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(fresh))
+	merged := make([]InAppPurchaseReceipt, 0, len(stored)+len(fresh))
+	for _, tx := range fresh {
+		seen[mergeKey(tx)] = true
+		merged = append(merged, tx)
+	}
+	for _, tx := range stored {
+		if !seen[mergeKey(tx)] {
+			merged = append(merged, tx)
+		}
+	}
+
+	sortByPurchaseDate(merged)
+	return merged
+}
+
+// mergeKey identifies a transaction across responses. Every renewal of a
+// subscription has its own web order line item identifier, which stays the
+// same when the App Store reissues the transaction.
+func mergeKey(tx InAppPurchaseReceipt) string {
+	if tx.WebOrderLineItemId != "" {
+		return "web_order_line_item_id:" + tx.WebOrderLineItemId
+	}
+	return "transaction_id:" + tx.TransactionId
+}
+
+func mergeRenewalInfo(stored, fresh []PendingRenewalInfo) []PendingRenewalInfo {
+	if len(stored) == 0 {
+		return fresh
+	}
+
+	seen := make(map[string]bool, len(fresh))
+	merged := make([]PendingRenewalInfo, 0, len(stored)+len(fresh))
+	for _, info := range fresh {
+		seen[info.OriginalTransactionId] = true
+		merged = append(merged, info)
+	}
+	for _, info := range stored {
+		if !seen[info.OriginalTransactionId] {
+			merged = append(merged, info)
+		}
+	}
+	return merged
+}
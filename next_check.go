@@ -0,0 +1,66 @@
+package storekit
+
+import "time"
+
+const (
+	// renewalCheckDelay is how long after a period expires to poll for its
+	// renewal, giving the App Store time to record it.
+	renewalCheckDelay = 5 * time.Minute
+
+	// billingRetryCheckInterval is how often to poll a subscription in billing
+	// retry, which the App Store may recover at any time.
+	billingRetryCheckInterval = 24 * time.Hour
+)
+
+// NextCheckTime returns when the subscription to the given product should be
+// verified again to pick up its next state change:
+//   - while the current period lasts, just after it expires when the
+//     subscription renews automatically, or when it expires otherwise;
+//   - during a billing grace period, when the grace period ends;
+//   - during billing retry, daily until the App Store gives up;
+//   - when a period expired without a renewal or billing retry being recorded
+//     while the subscription renews automatically, after the renewal check
+//     delay, so that a caller polling until the App Store records the renewal
+//     does not spin.
+//
+// It returns the zero time when no change is expected: the response holds no
+// subscription transaction for the product, the latest transaction was
+// refunded, or the subscription expired and does not renew.
+func (r *ReceiptResponse) NextCheckTime(productID string, now time.Time) time.Time {
+	tx, ok := latestTransaction(r.transactions(), productID)
+	if !ok || tx.ExpiresDateMs == 0 || tx.CancellationDateMs != 0 {
+		return time.Time{}
+	}
+	info, _ := renewalInfoOf(r.PendingRenewalInfo, tx)
+	renews := info.AutoRenewStatus.Bool()
+
+	expiresAt := timeFromMs(tx.ExpiresDateMs)
+	if now.Before(expiresAt) {
+		if renews {
+			return expiresAt.Add(renewalCheckDelay)
+		}
+		return expiresAt
+	}
+
+	if graceEnd, ok := info.GracePeriodExpiresAt(); ok && now.Before(graceEnd) {
+		return graceEnd
+	}
+
+	if info.IsInBillingRetry() {
+		deadline := expiresAt.Add(billingRetryPeriod)
+		if !now.Before(deadline) {
+			return time.Time{}
+		}
+		next := now.Add(billingRetryCheckInterval)
+		if next.After(deadline) {
+			return deadline
+		}
+		return next
+	}
+
+	if renews {
+		return now.Add(renewalCheckDelay)
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,19 @@
+package storekit
+
+import "testing"
+
+func TestNextCheckTimeBacksOffWhenRenewalIsLate(t *testing.T) {
+	resp := &ReceiptResponse{
+		LatestReceiptInfo: []LatestReceiptInfo{
+			{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 1000, ExpiresDateMs: 2000},
+		},
+		PendingRenewalInfo: []PendingRenewalInfo{
+			{ProductId: "basic", AutoRenewProductId: "basic", OriginalTransactionId: "1", AutoRenewStatus: AutoRenewStatusOn},
+		},
+	}
+
+	now := timeFromMs(5000)
+	if got, want := resp.NextCheckTime("basic", now), now.Add(renewalCheckDelay); !got.Equal(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
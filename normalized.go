@@ -0,0 +1,130 @@
+package storekit
+
+import "time"
+
+// NormalizedResponse is a typed view of a verifyReceipt response, with dates
+// as time values and flags as booleans, meant to be serialized or stored
+// directly.
+type NormalizedResponse struct {
+	Status      ReceiptResponseStatus `json:"status"`
+	Environment Environment           `json:"environment,omitempty"`
+
+	BundleId                   string     `json:"bundle_id,omitempty"`
+	ApplicationVersion         string     `json:"application_version,omitempty"`
+	OriginalApplicationVersion string     `json:"original_application_version,omitempty"`
+	ReceiptType                string     `json:"receipt_type,omitempty"`
+	ReceiptCreatedAt           *time.Time `json:"receipt_created_at,omitempty"`
+	RequestedAt                *time.Time `json:"requested_at,omitempty"`
+
+	// The transactions of both the latest receipt info and the receipt, without
+	// duplicates and ordered by purchase date.
+	Transactions []NormalizedTransaction `json:"transactions,omitempty"`
+
+	PendingRenewals []NormalizedRenewalInfo `json:"pending_renewals,omitempty"`
+}
+
+// NormalizedTransaction is a typed view of an in-app purchase transaction.
+type NormalizedTransaction struct {
+	TransactionId               string `json:"transaction_id"`
+	OriginalTransactionId       string `json:"original_transaction_id,omitempty"`
+	WebOrderLineItemId          string `json:"web_order_line_item_id,omitempty"`
+	ProductId                   string `json:"product_id"`
+	SubscriptionGroupIdentifier string `json:"subscription_group_identifier,omitempty"`
+	Quantity                    int    `json:"quantity,omitempty"`
+
+	PurchasedAt         *time.Time `json:"purchased_at,omitempty"`
+	OriginalPurchasedAt *time.Time `json:"original_purchased_at,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	CancelledAt         *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason  string     `json:"cancellation_reason,omitempty"`
+
+	IsTrialPeriod        bool   `json:"is_trial_period"`
+	IsInIntroOfferPeriod bool   `json:"is_in_intro_offer_period"`
+	IsUpgraded           bool   `json:"is_upgraded"`
+	FamilyShared         bool   `json:"family_shared"`
+	PromotionalOfferId   string `json:"promotional_offer_id,omitempty"`
+	OfferCodeRefName     string `json:"offer_code_ref_name,omitempty"`
+}
+
+// NormalizedRenewalInfo is a typed view of the pending renewal info of a
+// subscription.
+type NormalizedRenewalInfo struct {
+	ProductId             string             `json:"product_id"`
+	OriginalTransactionId string             `json:"original_transaction_id,omitempty"`
+	AutoRenewProductId    string             `json:"auto_renew_product_id,omitempty"`
+	AutoRenewEnabled      bool               `json:"auto_renew_enabled"`
+	ExpirationIntent      ExpirationIntent   `json:"expiration_intent,omitempty"`
+	InBillingRetry        bool               `json:"in_billing_retry"`
+	GracePeriodExpiresAt  *time.Time         `json:"grace_period_expires_at,omitempty"`
+	PriceConsentStatus    PriceConsentStatus `json:"price_consent_status,omitempty"`
+	OfferCodeRefName      string             `json:"offer_code_ref_name,omitempty"`
+}
+
+// Normalized returns the typed view of the response.
+func (r *ReceiptResponse) Normalized() *NormalizedResponse {
+	n := &NormalizedResponse{
+		Status:                     r.Status,
+		Environment:                r.Environment,
+		BundleId:                   r.Receipt.BundleId,
+		ApplicationVersion:         r.Receipt.ApplicationVersion,
+		OriginalApplicationVersion: r.Receipt.OriginalApplicationVersion,
+		ReceiptType:                r.Receipt.ReceiptType,
+		ReceiptCreatedAt:           optionalTimeFromMs(r.Receipt.ReceiptCreationDateMs),
+		RequestedAt:                optionalTimeFromMs(r.Receipt.RequestDateMs),
+	}
+
+	for _, tx := range r.sortedTransactions() {
+		n.Transactions = append(n.Transactions, normalizeTransaction(tx))
+	}
+	for _, info := range r.PendingRenewalInfo {
+		n.PendingRenewals = append(n.PendingRenewals, normalizeRenewalInfo(info))
+	}
+
+	return n
+}
+
+func normalizeTransaction(tx InAppPurchaseReceipt) NormalizedTransaction {
+	return NormalizedTransaction{
+		TransactionId:               tx.TransactionId,
+		OriginalTransactionId:       tx.OriginalTransactionId,
+		WebOrderLineItemId:          tx.WebOrderLineItemId,
+		ProductId:                   tx.ProductId,
+		SubscriptionGroupIdentifier: tx.SubscriptionGroupIdentifier,
+		Quantity:                    tx.Quantity,
+		PurchasedAt:                 optionalTimeFromMs(tx.PurchaseDateMs),
+		OriginalPurchasedAt:         optionalTimeFromMs(tx.OriginalPurchaseDateMs),
+		ExpiresAt:                   optionalTimeFromMs(tx.ExpiresDateMs),
+		CancelledAt:                 optionalTimeFromMs(tx.CancellationDateMs),
+		CancellationReason:          tx.CancellationReason,
+		IsTrialPeriod:               tx.IsTrialPeriod.Bool(),
+		IsInIntroOfferPeriod:        tx.IsInIntroOfferPeriod.Bool(),
+		IsUpgraded:                  tx.IsUpgraded.Bool(),
+		FamilyShared:                tx.IsFamilyShared(),
+		PromotionalOfferId:          tx.PromotionalOfferId,
+		OfferCodeRefName:            tx.OfferCodeRefName,
+	}
+}
+
+func normalizeRenewalInfo(info PendingRenewalInfo) NormalizedRenewalInfo {
+	return NormalizedRenewalInfo{
+		ProductId:             info.ProductId,
+		OriginalTransactionId: info.OriginalTransactionId,
+		AutoRenewProductId:    info.AutoRenewProductId,
+		AutoRenewEnabled:      info.AutoRenewStatus == AutoRenewStatusOn,
+		ExpirationIntent:      info.ExpirationIntent,
+		InBillingRetry:        info.IsInBillingRetry(),
+		GracePeriodExpiresAt:  optionalTimeFromMs(info.GracePeriodExpiresDateMs),
+		PriceConsentStatus:    info.PriceConsentStatus,
+		OfferCodeRefName:      info.OfferCodeRefName,
+	}
+}
+
+// optionalTimeFromMs converts UNIX epoch milliseconds to time, or nil when the
+// value is absent.
+func optionalTimeFromMs(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := timeFromMs(ms).UTC()
+	return &t
+}
@@ -1,5 +1,13 @@
 package storekit
 
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 // NotificationType is the type that describes the in-app purchase event for
 // which the App Store sent the notification.
 //
@@ -65,6 +73,13 @@ const (
 	// original_transaction_id and product_id identify the original transaction and
 	// product. The cancellation_reason contains the reason.
 	NotificationTypeRefund NotificationType = "REFUND"
+
+	// Indicates that an in-app purchase the user was entitled to through Family
+	// Sharing is no longer available through sharing. The App Store sends this
+	// notification when a purchaser disabled Family Sharing for a product, the
+	// purchaser or family member left the family group, or the purchaser asked
+	// for and received a refund.
+	NotificationTypeRevoke NotificationType = "REVOKE"
 )
 
 // UnifiedReceipt is an object that contains information about the most-recent,
@@ -74,7 +89,7 @@ const (
 type UnifiedReceipt struct {
 	// The environment for which App Store generated the receipt.
 	// Possible values: Sandbox, Production
-	Environment string `json:"environment,omitempty"`
+	Environment Environment `json:"environment,omitempty"`
 
 	// The latest Base64-encoded app receipt.
 	LatestReceipt []byte `json:"latest_receipt,omitempty"`
@@ -119,7 +134,7 @@ type Notification struct {
 	// that these values are different from those of the auto_renew_status in the
 	// receipt.
 	// Possible values: true, false
-	AutoRenewStatus string `json:"auto_renew_status,omitempty"`
+	AutoRenewStatus FlexBool `json:"auto_renew_status,omitempty"`
 
 	// The time at which the user turned on or off the renewal status for an
 	// auto-renewable subscription, in a date-time format similar to the ISO 8601
@@ -136,8 +151,8 @@ type Notification struct {
 	AutoRenewStatusChangeDatePst string `json:"auto_renew_status_change_date_pst,omitempty"`
 
 	// The environment for which App Store generated the receipt.
-	// Possible values: Sandbox, PROD
-	Environment string `json:"environment,omitempty"`
+	// Possible values: Sandbox, PROD. PROD decodes as EnvironmentProduction.
+	Environment Environment `json:"environment,omitempty"`
 
 	// The reason a subscription expired. This field is only present for an expired
 	// auto-renewable subscription. See expiration_intent for more information.
@@ -160,3 +175,62 @@ type Notification struct {
 	// A string that contains the app bundle version.
 	Bvrs string `json:"bvrs,omitempty"`
 }
+
+// NotificationOption customizes the checks DecodeNotificationV1 applies.
+type NotificationOption func(*notificationChecks)
+
+type notificationChecks struct {
+	sharedSecret string
+}
+
+// WithNotificationPassword makes DecodeNotificationV1 fail with
+// ErrNotificationPasswordMismatch unless the password of the notification
+// matches the given shared secret. The password is the only authenticity
+// signal of version 1 notifications, and a weak one; skip the check when
+// authenticity is enforced elsewhere, for instance at the network level.
+func WithNotificationPassword(sharedSecret string) NotificationOption {
+	return func(c *notificationChecks) {
+		c.sharedSecret = sharedSecret
+	}
+}
+
+// DecodeNotificationV1 parses the JSON body of a version 1 App Store server
+// notification. The transactions in the unified receipt are decoded into the
+// same types as the verifyReceipt response.
+func DecodeNotificationV1(body []byte, opts ...NotificationOption) (*Notification, error) {
+	checks := &notificationChecks{}
+	for _, opt := range opts {
+		opt(checks)
+	}
+
+	n := &Notification{}
+	err := json.Unmarshal(stripControlCharacters(body), n)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal app store notification")
+	}
+
+	if checks.sharedSecret != "" && subtle.ConstantTimeCompare([]byte(n.Password), []byte(checks.sharedSecret)) != 1 {
+		return nil, ErrNotificationPasswordMismatch
+	}
+
+	return n, nil
+}
+
+// Entitlement returns the entitlement of the given product at the given time as
+// described by the unified receipt of the notification.
+func (n *Notification) Entitlement(productID string, at time.Time) (*Entitlement, bool) {
+	return n.UnifiedReceipt.Entitlement(productID, at)
+}
+
+// Entitlement returns the entitlement of the given product at the given time
+// based on the latest receipt info of the unified receipt.
+func (u *UnifiedReceipt) Entitlement(productID string, at time.Time) (*Entitlement, bool) {
+	return entitlementOf(productID, at, fromLatestReceiptInfo(u.LatestReceiptInfo))
+}
+
+// IsFamilySharingRevoked reports whether the notification tells that access to
+// a family-shared purchase was revoked. The affected transactions are in the
+// latest receipt info of the unified receipt.
+func (n *Notification) IsFamilySharingRevoked() bool {
+	return n.NotificationType == NotificationTypeRevoke
+}
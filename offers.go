@@ -0,0 +1,62 @@
+package storekit
+
+// OfferPaymentMode is how the customer pays for a subscription offer.
+type OfferPaymentMode string
+
+const (
+	// The customer pays nothing for the offer periods.
+	OfferPaymentModeFreeTrial OfferPaymentMode = "FREE_TRIAL"
+
+	// The customer pays a discounted price for each offer period.
+	OfferPaymentModePayAsYouGo OfferPaymentMode = "PAY_AS_YOU_GO"
+
+	// The customer pays a discounted price once for all offer periods.
+	OfferPaymentModePayUpFront OfferPaymentMode = "PAY_UP_FRONT"
+)
+
+// OfferDiscount describes a promotional offer as configured in App Store
+// Connect. Receipts only carry the offer identifier, so the details come from
+// your own configuration.
+type OfferDiscount struct {
+	// A display name, such as "3 months at 50% off".
+	Name string
+
+	PaymentMode OfferPaymentMode
+
+	// The number of subscription periods the offer lasts.
+	NumberOfPeriods int
+}
+
+// OfferCatalog maps promotional offer identifiers to their discount.
+type OfferCatalog map[string]OfferDiscount
+
+// OfferCodeRedemptions returns the transactions purchased with an offer code,
+// keyed by the reference name of the offer code campaign and ordered by
+// purchase date.
+func (r *ReceiptResponse) OfferCodeRedemptions() map[string][]InAppPurchaseReceipt {
+	redemptions := make(map[string][]InAppPurchaseReceipt)
+	for _, tx := range r.sortedTransactions() {
+		if refName, ok := tx.OfferCode(); ok {
+			redemptions[refName] = append(redemptions[refName], tx)
+		}
+	}
+	return redemptions
+}
+
+// AppliedOffer returns the discount of the promotional offer redeemed for the
+// latest transaction of the given product, looked up in the catalog. It
+// returns false when no promotional offer applies or the catalog does not list
+// it.
+func (r *ReceiptResponse) AppliedOffer(productID string, catalog OfferCatalog) (OfferDiscount, bool) {
+	tx, ok := latestTransaction(r.transactions(), productID)
+	if !ok {
+		return OfferDiscount{}, false
+	}
+	offerID, ok := tx.PromotionalOffer()
+	if !ok {
+		return OfferDiscount{}, false
+	}
+
+	discount, ok := catalog[offerID]
+	return discount, ok
+}
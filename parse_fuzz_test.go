@@ -0,0 +1,31 @@
+//go:build go1.18
+// +build go1.18
+
+package storekit
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func FuzzParseResponse(f *testing.F) {
+	for _, tt := range malformedPayloads {
+		f.Add([]byte(tt.body))
+	}
+	f.Add([]byte(`{"status":0}`))
+	f.Add([]byte(receiptWithoutLatestInfo))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, p := range parsers {
+			_, err := p.parse(body)
+			if err == nil {
+				continue
+			}
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("%s: want a *DecodeError, got %T: %v", p.name, err, err)
+			}
+		}
+	})
+}
@@ -1,5 +1,7 @@
 package storekit
 
+import "time"
+
 // AutoRenewStatus is returned in the JSON response, in the
 // responseBody.Pending_renewal_info array.
 //
@@ -22,6 +24,23 @@ const (
 	AutoRenewStatusOn AutoRenewStatus = "1"
 )
 
+// Bool reports whether the subscription renews automatically.
+func (s AutoRenewStatus) Bool() bool {
+	return s == AutoRenewStatusOn
+}
+
+func (s *AutoRenewStatus) UnmarshalJSON(data []byte) error {
+	on, err := parseFlexBool(data)
+	if err != nil {
+		return err
+	}
+	*s = AutoRenewStatusOff
+	if on {
+		*s = AutoRenewStatusOn
+	}
+	return nil
+}
+
 // BillingRetryStatus indicates whether Apple is attempting to renew an expired
 // subscription automatically.
 //
@@ -56,6 +75,23 @@ const (
 	BillingRetryStatusAttemptingRenewal BillingRetryStatus = "1"
 )
 
+// Bool reports whether the App Store is attempting to renew the subscription.
+func (s BillingRetryStatus) Bool() bool {
+	return s == BillingRetryStatusAttemptingRenewal
+}
+
+func (s *BillingRetryStatus) UnmarshalJSON(data []byte) error {
+	attempting, err := parseFlexBool(data)
+	if err != nil {
+		return err
+	}
+	*s = BillingRetryStatusStoppedAttemptingRenewal
+	if attempting {
+		*s = BillingRetryStatusAttemptingRenewal
+	}
+	return nil
+}
+
 // ExpirationIntent is the reason a subscription expired.
 type ExpirationIntent string
 
@@ -77,6 +113,14 @@ const (
 	ExpirationIntentUnknown ExpirationIntent = "5"
 )
 
+// PriceConsentStatus is the price consent status for a subscription price
+// increase.
+//
+// The App Store asks for consent when you increase the price of a
+// subscription. Until the customer consents, the price_consent_status value is
+// "0" and the subscription does not renew at the new price.
+//
+// https://developer.apple.com/documentation/appstorereceipts/price_consent_status
 type PriceConsentStatus string
 
 const (
@@ -146,4 +190,34 @@ type PendingRenewalInfo struct {
 	// productIdentifier property of the SKPayment object stored in the
 	// transaction's payment property.
 	ProductId string `json:"product_id,omitempty"`
+
+	// The identifier of the promotional offer for an auto-renewable subscription
+	// that the user redeemed. You provide this field in the Offer Identifier
+	// field when you create the promotional offer in App Store Connect.
+	PromotionalOfferId string `json:"promotional_offer_id,omitempty"`
+}
+
+// OfferCode returns the reference name of the offer code the subscription
+// renews with. It returns false when no offer code was redeemed.
+func (r *PendingRenewalInfo) OfferCode() (refName string, ok bool) {
+	return r.OfferCodeRefName, r.OfferCodeRefName != ""
+}
+
+// GracePeriodExpiresAt returns the time the billing grace period of the
+// subscription ends. It returns false when the subscription is not in a grace
+// period.
+func (r *PendingRenewalInfo) GracePeriodExpiresAt() (time.Time, bool) {
+	return timeFromMs(r.GracePeriodExpiresDateMs), r.GracePeriodExpiresDateMs != 0
+}
+
+// IsInBillingRetry reports whether the App Store is attempting to renew the
+// expired subscription.
+func (r *PendingRenewalInfo) IsInBillingRetry() bool {
+	return r.IsInBillingRetryPeriod.Bool()
+}
+
+// PromotionalOffer returns the identifier of the promotional offer the
+// subscription renews with. It returns false when no promotional offer applies.
+func (r *PendingRenewalInfo) PromotionalOffer() (offerID string, ok bool) {
+	return r.PromotionalOfferId, r.PromotionalOfferId != ""
 }
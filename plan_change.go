@@ -0,0 +1,83 @@
+package storekit
+
+import "time"
+
+// ChangeKind is the direction of a change between two subscription products.
+type ChangeKind string
+
+const (
+	// The subscription keeps renewing to the same product.
+	ChangeKindNone ChangeKind = "none"
+
+	// The subscription changes to a higher ranked product.
+	ChangeKindUpgrade ChangeKind = "upgrade"
+
+	// The subscription changes to a lower ranked product.
+	ChangeKindDowngrade ChangeKind = "downgrade"
+
+	// The subscription changes to another product of the same rank, typically
+	// with a different duration.
+	ChangeKindCrossgrade ChangeKind = "crossgrade"
+
+	// The subscription changes to another product but no ranking tells in which
+	// direction.
+	ChangeKindUnknown ChangeKind = "unknown"
+)
+
+// ProductRanking returns the rank of a product within its subscription group,
+// higher ranks standing for higher service levels. The App Store does not
+// expose ranks in receipts, so they come from your own product catalog.
+type ProductRanking func(productID string) int
+
+// PendingPlanChange returns the product the subscription to the given product
+// renews to and how it compares with the current one. Without a ranking any
+// change is reported as ChangeKindUnknown. It returns false when the response
+// holds no pending renewal info for the product.
+func (r *ReceiptResponse) PendingPlanChange(productID string, rank ProductRanking) (newProductID string, kind ChangeKind, ok bool) {
+	info, ok := r.renewalInfo(productID)
+	if !ok {
+		return "", ChangeKindNone, false
+	}
+
+	newProductID = info.AutoRenewProductId
+	if newProductID == "" {
+		newProductID = productID
+	}
+
+	return newProductID, compareProducts(productID, newProductID, rank), true
+}
+
+// CurrentAndNextProduct returns the product of the subscription of the given
+// subscription group active at the given time and the product it renews to.
+// After a downgrade or a crossgrade is scheduled, the current period keeps
+// the current product while the next one points to the new product; otherwise
+// both are the same. It returns false when no subscription of the group is
+// active.
+func (r *ReceiptResponse) CurrentAndNextProduct(groupID string, at time.Time) (current, next string, ok bool) {
+	sub, ok := r.ActiveSubscriptionInGroup(groupID, at)
+	if !ok {
+		return "", "", false
+	}
+
+	next = sub.AutoRenewProductId
+	if next == "" {
+		next = sub.ProductId
+	}
+	return sub.ProductId, next, true
+}
+
+// compareProducts returns the kind of change from one product to another.
+func compareProducts(from, to string, rank ProductRanking) ChangeKind {
+	switch {
+	case from == to:
+		return ChangeKindNone
+	case rank == nil:
+		return ChangeKindUnknown
+	case rank(to) > rank(from):
+		return ChangeKindUpgrade
+	case rank(to) < rank(from):
+		return ChangeKindDowngrade
+	default:
+		return ChangeKindCrossgrade
+	}
+}
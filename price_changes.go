@@ -0,0 +1,63 @@
+package storekit
+
+import "time"
+
+// PriceResolver returns the price charged for the period the transaction pays
+// for, in milliunits of the storefront currency, or false when it is unknown.
+// verifyReceipt responses carry no price, so it comes from your own catalog,
+// typically keyed by product and by the offer applied to the transaction.
+type PriceResolver func(tx InAppPurchaseReceipt) (priceMilliunits int64, ok bool)
+
+// PriceChange is a change of the price charged between two consecutive periods
+// of a subscription.
+type PriceChange struct {
+	OriginalTransactionId string
+
+	// The transaction of the first period charged the new price.
+	TransactionId      string
+	WebOrderLineItemId string
+
+	// The start of the first period charged the new price.
+	ChangedAt time.Time
+
+	// The prices before and after the change, in milliunits.
+	OldPriceMilliunits int64
+	NewPriceMilliunits int64
+}
+
+// PriceChanges returns the changes of the price charged between consecutive
+// periods of the subscriptions to the given product, ordered by purchase date.
+// Each period is identified by its web order line item identifier and priced
+// by the resolver; periods the resolver cannot price are skipped.
+//
+// Since the receipt does not record what was charged, the result is only as
+// accurate as the resolver: it reflects the catalog price of each period, not
+// storefront or tax adjustments, and older periods are missing from a response
+// verified excluding old transactions.
+func (r *ReceiptResponse) PriceChanges(productID string, price PriceResolver) []PriceChange {
+	var changes []PriceChange
+	previous := make(map[string]int64)
+	for _, tx := range r.sortedTransactions() {
+		if tx.ProductId != productID || tx.ExpiresDateMs == 0 {
+			continue
+		}
+		current, ok := price(tx)
+		if !ok {
+			continue
+		}
+
+		if old, ok := previous[tx.OriginalTransactionId]; ok && old != current {
+			changes = append(changes, PriceChange{
+				OriginalTransactionId: tx.OriginalTransactionId,
+				TransactionId:         tx.TransactionId,
+				WebOrderLineItemId:    tx.WebOrderLineItemId,
+				ChangedAt:             timeFromMs(tx.PurchaseDateMs),
+				OldPriceMilliunits:    old,
+				NewPriceMilliunits:    current,
+			})
+		}
+		previous[tx.OriginalTransactionId] = current
+	}
+
+	return changes
+}
@@ -0,0 +1,56 @@
+package storekit
+
+// ProductType is the type of an in-app purchase product. The values match the
+// ones used by the App Store Server API.
+type ProductType string
+
+const (
+	ProductTypeAutoRenewable ProductType = "Auto-Renewable Subscription"
+	ProductTypeNonRenewing   ProductType = "Non-Renewing Subscription"
+	ProductTypeConsumable    ProductType = "Consumable"
+	ProductTypeNonConsumable ProductType = "Non-Consumable"
+)
+
+// ProductCatalog maps product identifiers to their type, as configured in App
+// Store Connect.
+type ProductCatalog map[string]ProductType
+
+// typeOf classifies the transaction. Products listed in the catalog get their
+// listed type. Otherwise transactions that expire are auto-renewable
+// subscriptions and the others are assumed to be non-consumables, since the
+// receipt no longer lists consumables once the app finishes their
+// transactions.
+func (c ProductCatalog) typeOf(tx InAppPurchaseReceipt) ProductType {
+	if t, ok := c[tx.ProductId]; ok {
+		return t
+	}
+	if tx.ExpiresDateMs != 0 {
+		return ProductTypeAutoRenewable
+	}
+	return ProductTypeNonConsumable
+}
+
+// NonConsumables returns the non-consumable purchases of the receipt. The
+// receipt does not label product types, so pass a catalog to classify
+// products; without one, every purchase that does not expire counts as
+// non-consumable.
+func (r *ReceiptResponse) NonConsumables(catalog ProductCatalog) []InAppPurchaseReceipt {
+	return r.inAppOfType(catalog, ProductTypeNonConsumable)
+}
+
+// Consumables returns the consumable purchases of the receipt that the app has
+// not finished yet. Only products listed as consumable in the catalog are
+// returned.
+func (r *ReceiptResponse) Consumables(catalog ProductCatalog) []InAppPurchaseReceipt {
+	return r.inAppOfType(catalog, ProductTypeConsumable)
+}
+
+func (r *ReceiptResponse) inAppOfType(catalog ProductCatalog, productType ProductType) []InAppPurchaseReceipt {
+	var txs []InAppPurchaseReceipt
+	for _, tx := range r.Receipt.InApp {
+		if catalog.typeOf(tx) == productType {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
@@ -22,3 +22,21 @@ type ReceiptRequest struct {
 	// for any subscriptions.
 	ExcludeOldTransactions bool `json:"exclude-old-transactions,omitempty"`
 }
+
+// RequestOption customizes the receipt request built by the client.
+type RequestOption func(*ReceiptRequest)
+
+// WithPassword sets the app’s shared secret sent with the request.
+func WithPassword(password string) RequestOption {
+	return func(r *ReceiptRequest) {
+		r.Password = password
+	}
+}
+
+// WithExcludeOldTransactions sets whether the response includes only the latest
+// renewal transaction of each subscription.
+func WithExcludeOldTransactions(exclude bool) RequestOption {
+	return func(r *ReceiptRequest) {
+		r.ExcludeOldTransactions = exclude
+	}
+}
@@ -1,5 +1,7 @@
 package storekit
 
+import "encoding/base64"
+
 // ReceiptResponseStatus is the status of the app receipt. The value for status
 // is 0 if the receipt is valid, or a status code if there is an error. The
 // status code reflects the status of the app receipt as a whole. For example,
@@ -40,6 +42,9 @@ const (
 	// is returned to your server, the receipt data is also decoded and returned as
 	// part of the response. Only returned for iOS 6-style transaction receipts for
 	// auto-renewable subscriptions.
+	//
+	// Note that the receipt itself verified successfully: the status tells that
+	// the subscription lapsed, not that verification failed.
 	ReceiptResponseStatusValidButSubscriptionExpired ReceiptResponseStatus = 21006
 
 	// This receipt is from the test environment, but it was sent to the production
@@ -53,18 +58,32 @@ const (
 	// Internal data access error. Try again later.
 	ReceiptResponseStatusBadAccess ReceiptResponseStatus = 21009
 
-	// The user account cannot be found or has been deleted.
+	// The user account cannot be found or has been deleted. The receipt could
+	// not be authorized: treat it as if no purchase was ever made, and deny
+	// access rather than retry.
 	ReceiptResponseStatusCouldNotBeAuthorized ReceiptResponseStatus = 21010
 
 	// Status codes 21100-21199 are various internal data access errors.
+
+	// A general internal error. Apple recommends retrying it, so the client
+	// does even when the response is not flagged as retryable, and at least
+	// once with a jittered backoff even when the retry policy disables
+	// retrying.
+	ReceiptResponseStatusInternalError ReceiptResponseStatus = 21199
 )
 
+// isInternalError reports whether the status is one of the 21100-21199
+// internal data access errors.
+func (s ReceiptResponseStatus) isInternalError() bool {
+	return s >= 21100 && s <= 21199
+}
+
 // ReceiptResponse is the JSON data returned in the response from the App Store.
 // https://developer.apple.com/documentation/appstorereceipts/responsebody
 type ReceiptResponse struct {
 	// The environment for which the receipt was generated.
 	// Possible values: Sandbox, Production
-	Environment string `json:"environment,omitempty"`
+	Environment Environment `json:"environment,omitempty"`
 
 	// IsRetryable is an indicator that an error occurred during the request. A
 	// value of 1 indicates a temporary issue; retry validation for this receipt at
@@ -95,3 +114,13 @@ type ReceiptResponse struct {
 	// status code reflects the status of the app receipt as a whole.
 	Status ReceiptResponseStatus `json:"status,omitempty"`
 }
+
+// LatestReceiptData returns the latest app receipt in the base64 form expected
+// by ReceiptRequest, for verifying it again later. It returns false when the
+// App Store did not return a latest receipt.
+func (r *ReceiptResponse) LatestReceiptData() (string, bool) {
+	if len(r.LatestReceipt) == 0 {
+		return "", false
+	}
+	return base64.StdEncoding.EncodeToString(r.LatestReceipt), true
+}
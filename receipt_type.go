@@ -0,0 +1,55 @@
+package storekit
+
+import "strings"
+
+// ReceiptType is the type of a receipt, which corresponds to the environment
+// in which the app or the Volume Purchase Program (VPP) purchase was made.
+type ReceiptType string
+
+const (
+	ReceiptTypeProduction           ReceiptType = "Production"
+	ReceiptTypeProductionVPP        ReceiptType = "ProductionVPP"
+	ReceiptTypeProductionSandbox    ReceiptType = "ProductionSandbox"
+	ReceiptTypeProductionVPPSandbox ReceiptType = "ProductionVPPSandbox"
+)
+
+// Type returns the type of the receipt.
+func (r *Receipt) Type() ReceiptType {
+	return ReceiptType(r.ReceiptType)
+}
+
+// Environment returns the environment the receipt type corresponds to. Types
+// ending in Sandbox belong to the sandbox even though they start with
+// Production. It returns false for an empty or unknown type.
+func (t ReceiptType) Environment() (Environment, bool) {
+	switch t {
+	case ReceiptTypeProduction, ReceiptTypeProductionVPP:
+		return EnvironmentProduction, true
+	case ReceiptTypeProductionSandbox, ReceiptTypeProductionVPPSandbox:
+		return EnvironmentSandbox, true
+	default:
+		return "", false
+	}
+}
+
+// IsVPP reports whether the receipt is for a Volume Purchase Program purchase.
+func (t ReceiptType) IsVPP() bool {
+	return strings.Contains(string(t), "VPP")
+}
+
+// IsXcodeEnvironment reports whether the receipt was generated by StoreKit
+// testing in Xcode rather than by the App Store.
+func (r *ReceiptResponse) IsXcodeEnvironment() bool {
+	return r.Environment == EnvironmentXcode
+}
+
+// EnvironmentMismatch reports whether the environment the App Store reports for
+// the response disagrees with the type of the receipt. It returns false when
+// either is missing or unknown.
+func (r *ReceiptResponse) EnvironmentMismatch() bool {
+	env, ok := r.Receipt.Type().Environment()
+	if !ok || r.Environment == "" {
+		return false
+	}
+	return r.Environment != env
+}
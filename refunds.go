@@ -0,0 +1,58 @@
+package storekit
+
+import "time"
+
+// CancellationReason is the reason for a refunded transaction.
+//
+// https://developer.apple.com/documentation/appstorereceipts/cancellation_reason
+type CancellationReason string
+
+const (
+	// The transaction was canceled for another reason, for example if the
+	// customer made the purchase accidentally.
+	CancellationReasonOther CancellationReason = "0"
+
+	// The customer canceled the transaction due to an actual or perceived issue
+	// within your app.
+	CancellationReasonAppIssue CancellationReason = "1"
+)
+
+// RefundedTransactions returns the transactions that Apple customer support
+// refunded, from both the latest receipt info and the in-app purchases of the
+// receipt, ordered by purchase date. The reason of each refund is given by its
+// RefundReason. Subscription periods canceled by an upgrade are not refunds and
+// are left out.
+func (r *ReceiptResponse) RefundedTransactions() []InAppPurchaseReceipt {
+	var refunded []InAppPurchaseReceipt
+	for _, tx := range r.sortedTransactions() {
+		if tx.IsRefunded() {
+			refunded = append(refunded, tx)
+		}
+	}
+	return refunded
+}
+
+// RefundAffectsActivePeriod reports whether the refund of the transaction with
+// the given identifier revokes access at the given time. A refunded
+// subscription period only matters while it would have been running: the
+// refund of a period that already ended does not affect current access.
+// Refunds of products that do not expire always affect access. A period
+// canceled by an upgrade is not a refund: access carries on with the new
+// product.
+func (r *ReceiptResponse) RefundAffectsActivePeriod(transactionID string, at time.Time) bool {
+	for _, tx := range r.sortedTransactions() {
+		if tx.TransactionId != transactionID {
+			continue
+		}
+		if !tx.IsRefunded() {
+			return false
+		}
+		if tx.ExpiresDateMs == 0 {
+			return true
+		}
+
+		return !at.Before(timeFromMs(tx.PurchaseDateMs)) && at.Before(timeFromMs(tx.ExpiresDateMs))
+	}
+
+	return false
+}
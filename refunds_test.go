@@ -0,0 +1,81 @@
+package storekit
+
+import "testing"
+
+// refundFixture holds a refunded consumable, a refunded subscription period
+// canceled for an app issue, a period canceled by an upgrade and a regular
+// renewal.
+func refundFixture() *ReceiptResponse {
+	return &ReceiptResponse{
+		LatestReceiptInfo: []LatestReceiptInfo{
+			{ProductId: "basic", TransactionId: "10", OriginalTransactionId: "10", PurchaseDateMs: 1000, ExpiresDateMs: 2000},
+			{ProductId: "basic", TransactionId: "11", OriginalTransactionId: "10", PurchaseDateMs: 2000, ExpiresDateMs: 3000, CancellationDateMs: 2500, IsUpgraded: true},
+			{ProductId: "premium", TransactionId: "12", OriginalTransactionId: "10", PurchaseDateMs: 2500, ExpiresDateMs: 3500, CancellationDateMs: 2800, CancellationReason: "1"},
+		},
+		Receipt: Receipt{InApp: []InAppPurchaseReceipt{
+			{ProductId: "coins", TransactionId: "20", PurchaseDateMs: 1500, CancellationDateMs: 1600, CancellationReason: "0"},
+		}},
+	}
+}
+
+func TestRefundedTransactionsLeavesUpgradesOut(t *testing.T) {
+	refunded := refundFixture().RefundedTransactions()
+
+	if len(refunded) != 2 || refunded[0].TransactionId != "20" || refunded[1].TransactionId != "12" {
+		t.Fatalf("want transactions 20 and 12 by purchase date, got %+v", refunded)
+	}
+}
+
+func TestRefundReason(t *testing.T) {
+	resp := refundFixture()
+	tests := []struct {
+		name string
+		tx   InAppPurchaseReceipt
+		want CancellationReason
+		ok   bool
+	}{
+		{"renewal", InAppPurchaseReceipt(resp.LatestReceiptInfo[0]), "", false},
+		{"upgraded", InAppPurchaseReceipt(resp.LatestReceiptInfo[1]), "", false},
+		{"app issue", InAppPurchaseReceipt(resp.LatestReceiptInfo[2]), CancellationReasonAppIssue, true},
+		{"other", resp.Receipt.InApp[0], CancellationReasonOther, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := tt.tx.RefundReason()
+			if ok != tt.ok || (ok && reason != tt.want) {
+				t.Fatalf("want %q, %v, got %q, %v", tt.want, tt.ok, reason, ok)
+			}
+			info := LatestReceiptInfo(tt.tx)
+			if _, infoOK := info.RefundReason(); infoOK != tt.ok {
+				t.Fatalf("want the latest receipt info to agree, got %v", infoOK)
+			}
+		})
+	}
+}
+
+func TestRefundAffectsActivePeriod(t *testing.T) {
+	resp := refundFixture()
+	tests := []struct {
+		name          string
+		transactionID string
+		atMs          int64
+		want          bool
+	}{
+		{"before the refunded period", "12", 2499, false},
+		{"at the start of the refunded period", "12", 2500, true},
+		{"within the refunded period", "12", 3000, true},
+		{"at the end of the refunded period", "12", 3500, false},
+		{"after the refunded period", "12", 4000, false},
+		{"upgraded period", "11", 2600, false},
+		{"renewal", "10", 1500, false},
+		{"refunded consumable", "20", 9000, true},
+		{"unknown transaction", "99", 1500, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resp.RefundAffectsActivePeriod(tt.transactionID, timeFromMs(tt.atMs)); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
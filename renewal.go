@@ -0,0 +1,50 @@
+package storekit
+
+import "time"
+
+// PriceIncreasePending reports whether the App Store is waiting for the
+// customer to consent to a price increase of the subscription to the given
+// product. It also returns the product the subscription renews to, as a
+// pending increase can come with a change of the renewing product.
+func (r *ReceiptResponse) PriceIncreasePending(productID string) (autoRenewProductID string, pending bool) {
+	info, ok := r.renewalInfo(productID)
+	if !ok {
+		return "", false
+	}
+
+	return info.AutoRenewProductId, info.PriceConsentStatus == PriceConsentStatusAwaitingConsent
+}
+
+// AutoRenewDisabled reports whether the customer turned off automatic renewal
+// of the subscription to the given product, so that it expires at the end of
+// the current period.
+//
+// The receipt only tells the current renewal status, not when it changed. The
+// time is only carried by the DID_CHANGE_RENEWAL_STATUS notification; see
+// Notification.AutoRenewDisabledAt.
+func (r *ReceiptResponse) AutoRenewDisabled(productID string) bool {
+	info, ok := r.renewalInfo(productID)
+	return ok && info.AutoRenewStatus == AutoRenewStatusOff
+}
+
+// AutoRenewDisabledAt returns the time the customer turned off automatic
+// renewal when the notification reports it. It returns false for any other
+// notification, including one reporting that renewal was turned back on.
+func (n *Notification) AutoRenewDisabledAt() (time.Time, bool) {
+	if n.NotificationType != NotificationTypeDidChangeRenewalStatus || n.AutoRenewStatus.Bool() || n.AutoRenewStatusChangeDateMs == 0 {
+		return time.Time{}, false
+	}
+	return timeFromMs(n.AutoRenewStatusChangeDateMs), true
+}
+
+// renewalInfo returns the pending renewal info of the subscription to the
+// given product.
+func (r *ReceiptResponse) renewalInfo(productID string) (PendingRenewalInfo, bool) {
+	for _, info := range r.PendingRenewalInfo {
+		if info.ProductId == productID {
+			return info, true
+		}
+	}
+
+	return PendingRenewalInfo{}, false
+}
@@ -0,0 +1,92 @@
+package storekit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy controls how failed requests to the App Store are retried.
+type RetryPolicy struct {
+	// The number of retries after the first attempt. Zero disables retrying.
+	MaxRetries int
+
+	// The delay before the first retry. It doubles on every subsequent retry.
+	Backoff time.Duration
+
+	// The upper bound of the delay between retries. Zero leaves it unbounded.
+	MaxBackoff time.Duration
+}
+
+const (
+	// internalErrorRetries is the least number of retries of the general 21199
+	// internal error, applied even when the retry policy disables retrying.
+	internalErrorRetries = 1
+
+	// internalErrorBackoff is the delay before retrying the general 21199
+	// internal error when the retry policy sets none.
+	internalErrorBackoff = time.Second
+)
+
+// defaultNonRetryableStatuses returns the statuses that retrying the same
+// request cannot fix.
+func defaultNonRetryableStatuses() map[ReceiptResponseStatus]bool {
+	return map[ReceiptResponseStatus]bool{
+		ReceiptResponseStatusAppStoreCannotRead:             true,
+		ReceiptResponseStatusNoLongerSent:                   true,
+		ReceiptResponseStatusNotAuthenticated:               true,
+		ReceiptResponseStatusSharedSecretDoesNotMatch:       true,
+		ReceiptResponseStatusValidButSubscriptionExpired:    true,
+		ReceiptResponseStatusSandboxReceiptSentToProduction: true,
+		ReceiptResponseStatusProductionReceiptSentToSandbox: true,
+		ReceiptResponseStatusCouldNotBeAuthorized:           true,
+	}
+}
+
+// delay returns the backoff before the given retry, counted from zero.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 0; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// jitter returns a random duration between half the given one and the full
+// one.
+func (c *client) jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+
+	c.jitterMu.Lock()
+	defer c.jitterMu.Unlock()
+	return half + time.Duration(c.jitterRand.Int63n(int64(d-half)+1))
+}
+
+// isServerError reports whether the App Store failed the request with an HTTP
+// 5xx status.
+func isServerError(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusInternalServerError
+}
+
+// sleep waits on the clock for the given duration unless the context is done
+// first.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
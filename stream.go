@@ -0,0 +1,133 @@
+package storekit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// parseLatestOnly decodes the App Store response token by token. Transactions
+// of the latest receipt info are decoded one at a time and only the latest one
+// of each product is kept, while the in-app purchases of the receipt are
+// skipped. Like parseResponse, it requires a non-null status and rejects
+// anything after the top-level object.
+func parseLatestOnly(body []byte) (*ReceiptResponse, error) {
+	dec := json.NewDecoder(bytes.NewReader(stripControlCharacters(body)))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, newDecodeError(err)
+	}
+
+	resp := &ReceiptResponse{}
+	var status *ReceiptResponseStatus
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, newDecodeError(err)
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "latest_receipt_info":
+			resp.LatestReceiptInfo, err = decodeLatestPerProduct(dec)
+		case "receipt":
+			// The outer field takes precedence over the embedded one, which keeps
+			// the in-app purchases from being decoded:
+			var receipt struct {
+				Receipt
+				InApp json.RawMessage `json:"in_app"`
+			}
+			err = dec.Decode(&receipt)
+			resp.Receipt = receipt.Receipt
+		case "status":
+			err = dec.Decode(&status)
+		case "environment":
+			err = dec.Decode(&resp.Environment)
+		case "is-retryable":
+			err = dec.Decode(&resp.IsRetryable)
+		case "latest_receipt":
+			err = dec.Decode(&resp.LatestReceipt)
+		case "pending_renewal_info":
+			err = dec.Decode(&resp.PendingRenewalInfo)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, fieldDecodeError(key, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, newDecodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, newDecodeError(errors.New("unexpected data after top-level object"))
+	}
+
+	if status == nil {
+		return nil, newDecodeError(errors.New("missing status"))
+	}
+	resp.Status = *status
+
+	return resp, nil
+}
+
+// fieldDecodeError reports an error decoding the value of the given top-level
+// key, prefixing the path of the offending field with the key, since values
+// decoded on their own only know their relative path.
+func fieldDecodeError(key string, err error) *DecodeError {
+	e := newDecodeError(err)
+	if e.Field == "" {
+		e.Field = key
+	} else {
+		e.Field = key + "." + e.Field
+	}
+	return e
+}
+
+// decodeLatestPerProduct decodes a latest receipt info array, keeping the
+// latest transaction of each product in order of first appearance. A null
+// array decodes as empty.
+func decodeLatestPerProduct(dec *json.Decoder) ([]LatestReceiptInfo, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, errors.Errorf("expected [, got %v", tok)
+	}
+
+	var latest []LatestReceiptInfo
+	index := make(map[string]int)
+	for dec.More() {
+		var info LatestReceiptInfo
+		if err := dec.Decode(&info); err != nil {
+			return nil, err
+		}
+
+		i, ok := index[info.ProductId]
+		switch {
+		case !ok:
+			index[info.ProductId] = len(latest)
+			latest = append(latest, info)
+		case isLater(InAppPurchaseReceipt(info), InAppPurchaseReceipt(latest[i])):
+			latest[i] = info
+		}
+	}
+
+	_, err = dec.Token()
+	return latest, err
+}
+
+// expectDelim consumes the next token, failing unless it is the delimiter.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return errors.Errorf("expected %s, got %v", delim, tok)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package storekit
+
+import "time"
+
+// Subscription combines the latest transaction of an auto-renewable
+// subscription with its pending renewal information.
+type Subscription struct {
+	// The unique identifier of the product of the latest transaction.
+	ProductId string
+
+	// The transaction identifier of the original purchase, shared by every
+	// transaction of the subscription.
+	OriginalTransactionId string
+
+	// The identifier of the latest transaction.
+	TransactionId string
+
+	// The identifier of the subscription group the subscription belongs to.
+	SubscriptionGroupIdentifier string
+
+	// The time the current subscription period expires or renews.
+	ExpiresAt time.Time
+
+	// The time Apple customer support canceled the latest transaction. Zero when
+	// it was not canceled.
+	CancelledAt time.Time
+
+	// The product the subscription renews to at the end of the current period.
+	AutoRenewProductId string
+
+	// The current renewal status of the subscription.
+	AutoRenewStatus AutoRenewStatus
+
+	// The reason the subscription expired, if it did.
+	ExpirationIntent ExpirationIntent
+
+	// Whether the App Store is attempting to renew the expired subscription.
+	IsInBillingRetryPeriod bool
+
+	// The time the billing grace period ends. Zero when the subscription is not in
+	// a grace period.
+	GracePeriodExpiresAt time.Time
+
+	// Whether the current period is a free trial.
+	IsTrialPeriod bool
+
+	// Whether the current period is an introductory price period.
+	IsInIntroOfferPeriod bool
+
+	// The identifier of the promotional offer redeemed for the current period.
+	PromotionalOfferId string
+
+	// The reference name of the offer code redeemed for the current period.
+	OfferCodeRefName string
+}
+
+// Subscriptions returns one entry per auto-renewable subscription found in the
+// latest receipt info, or in the receipt when there is none, joined with the
+// matching pending renewal info. Entries keep the order in which subscriptions
+// first appear in the response.
+func (r *ReceiptResponse) Subscriptions() []Subscription {
+	return subscriptionsOf(r.transactions(), r.PendingRenewalInfo)
+}
+
+// ActiveSubscriptionInGroup returns the subscription of the given subscription
+// group that is active at the given time. Only one subscription per group can
+// be active at once.
+func (r *ReceiptResponse) ActiveSubscriptionInGroup(groupID string, at time.Time) (Subscription, bool) {
+	var active Subscription
+	found := false
+	for _, sub := range r.Subscriptions() {
+		if sub.SubscriptionGroupIdentifier != groupID || !sub.IsActive(at) {
+			continue
+		}
+		if !found || sub.ExpiresAt.After(active.ExpiresAt) {
+			active = sub
+			found = true
+		}
+	}
+
+	return active, found
+}
+
+// TransactionsByGroup returns the subscription transactions of both the latest
+// receipt info and the receipt, without duplicates and ordered by purchase
+// date, keyed by subscription group identifier.
+//
+// The group comes from the subscription_group_identifier of each transaction,
+// which the latest receipt info carries. In-app purchases of older receipts
+// may lack it, so the given map from product identifier to group identifier
+// fills the gap; it may be nil. Transactions whose group is unknown either
+// way are left out, as are products that are not subscriptions.
+func (r *ReceiptResponse) TransactionsByGroup(productGroups map[string]string) map[string][]InAppPurchaseReceipt {
+	groups := make(map[string][]InAppPurchaseReceipt)
+	for _, tx := range r.sortedTransactions() {
+		groupID := tx.SubscriptionGroupIdentifier
+		if groupID == "" {
+			groupID = productGroups[tx.ProductId]
+		}
+		if groupID == "" {
+			continue
+		}
+		groups[groupID] = append(groups[groupID], tx)
+	}
+	return groups
+}
+
+// IsActive reports whether the current period of the subscription covers the
+// given time and the latest transaction was not canceled. The period covers
+// times strictly before its expiration.
+func (s *Subscription) IsActive(at time.Time) bool {
+	return s.CancelledAt.IsZero() && at.Before(s.ExpiresAt)
+}
+
+// IsNewSubscriber reports whether the latest transaction of the given product
+// is the very first purchase of its subscription. Resubscribing after a lapse
+// keeps the original transaction identifier but issues a new transaction
+// identifier, so it does not count as a new subscriber.
+func (r *ReceiptResponse) IsNewSubscriber(productID string) bool {
+	txs := r.sortedTransactions()
+	latest, ok := latestTransaction(txs, productID)
+	if !ok || latest.TransactionId != latest.OriginalTransactionId {
+		return false
+	}
+
+	for _, tx := range txs {
+		if tx.OriginalTransactionId == latest.OriginalTransactionId && tx.TransactionId != latest.TransactionId {
+			return false
+		}
+	}
+
+	return true
+}
+
+func subscriptionsOf(txs []InAppPurchaseReceipt, renewals []PendingRenewalInfo) []Subscription {
+	var order []string
+	latest := make(map[string]InAppPurchaseReceipt)
+	for _, tx := range txs {
+		if tx.ExpiresDateMs == 0 {
+			// Only subscriptions expire.
+			continue
+		}
+		current, ok := latest[tx.OriginalTransactionId]
+		if !ok {
+			order = append(order, tx.OriginalTransactionId)
+		}
+		if !ok || isLater(tx, current) {
+			latest[tx.OriginalTransactionId] = tx
+		}
+	}
+
+	subs := make([]Subscription, 0, len(order))
+	for _, id := range order {
+		tx := latest[id]
+		sub := Subscription{
+			ProductId:                   tx.ProductId,
+			OriginalTransactionId:       tx.OriginalTransactionId,
+			TransactionId:               tx.TransactionId,
+			SubscriptionGroupIdentifier: tx.SubscriptionGroupIdentifier,
+			ExpiresAt:                   timeFromMs(tx.ExpiresDateMs),
+			CancelledAt:                 timeFromMs(tx.CancellationDateMs),
+			IsTrialPeriod:               tx.IsTrialPeriod.Bool(),
+			IsInIntroOfferPeriod:        tx.IsInIntroOfferPeriod.Bool(),
+			PromotionalOfferId:          tx.PromotionalOfferId,
+			OfferCodeRefName:            tx.OfferCodeRefName,
+		}
+		if info, ok := renewalInfoOf(renewals, tx); ok {
+			sub.AutoRenewProductId = info.AutoRenewProductId
+			sub.AutoRenewStatus = info.AutoRenewStatus
+			sub.ExpirationIntent = info.ExpirationIntent
+			sub.IsInBillingRetryPeriod = info.IsInBillingRetry()
+			sub.GracePeriodExpiresAt = timeFromMs(info.GracePeriodExpiresDateMs)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// renewalInfoOf finds the pending renewal info of the subscription the
+// transaction belongs to, matching by original transaction identifier first
+// and by product identifier otherwise.
+func renewalInfoOf(renewals []PendingRenewalInfo, tx InAppPurchaseReceipt) (PendingRenewalInfo, bool) {
+	for _, info := range renewals {
+		if info.OriginalTransactionId != "" && info.OriginalTransactionId == tx.OriginalTransactionId {
+			return info, true
+		}
+	}
+	for _, info := range renewals {
+		if info.OriginalTransactionId == "" && info.ProductId == tx.ProductId {
+			return info, true
+		}
+	}
+
+	return PendingRenewalInfo{}, false
+}
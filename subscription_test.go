@@ -0,0 +1,159 @@
+package storekit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscriptionIsActiveExpiryBoundary(t *testing.T) {
+	expiresAt := time.Unix(1610000000, 0)
+	sub := &Subscription{ProductId: "monthly", ExpiresAt: expiresAt}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"just before", expiresAt.Add(-time.Millisecond), true},
+		{"at", expiresAt, false},
+		{"just after", expiresAt.Add(time.Millisecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sub.IsActive(tt.at); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSubscriptionIsActiveWhenCancelled(t *testing.T) {
+	sub := &Subscription{ExpiresAt: time.Unix(1610000000, 0), CancelledAt: time.Unix(1605000000, 0)}
+	if sub.IsActive(time.Unix(1600000000, 0)) {
+		t.Fatal("want a cancelled subscription inactive")
+	}
+}
+
+func TestRemainingTrialPeriodExpiryBoundary(t *testing.T) {
+	expiresAt := time.Unix(1610000000, 0)
+	resp := &ReceiptResponse{LatestReceiptInfo: []LatestReceiptInfo{{
+		ProductId:     "monthly",
+		TransactionId: "1",
+		ExpiresDateMs: expiresAt.UnixNano() / int64(time.Millisecond),
+		IsTrialPeriod: true,
+	}}}
+
+	if left, ok := resp.RemainingTrialPeriod("monthly", expiresAt.Add(-time.Millisecond)); !ok || left != time.Millisecond {
+		t.Fatalf("want 1ms left just before expiry, got %v, %v", left, ok)
+	}
+	for _, at := range []time.Time{expiresAt, expiresAt.Add(time.Millisecond)} {
+		if _, ok := resp.RemainingTrialPeriod("monthly", at); ok {
+			t.Fatalf("want the trial over at %v", at)
+		}
+	}
+}
+
+func TestSubscriptionsJoinRenewalInfo(t *testing.T) {
+	resp := &ReceiptResponse{
+		LatestReceiptInfo: []LatestReceiptInfo{
+			{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 1000},
+			{ProductId: "basic", TransactionId: "2", OriginalTransactionId: "1", PurchaseDateMs: 1000, ExpiresDateMs: 2000},
+			{ProductId: "premium", TransactionId: "5", OriginalTransactionId: "5", PurchaseDateMs: 500, ExpiresDateMs: 1500},
+			{ProductId: "coins", TransactionId: "9", OriginalTransactionId: "9", PurchaseDateMs: 700},
+		},
+		PendingRenewalInfo: []PendingRenewalInfo{
+			{ProductId: "basic", AutoRenewProductId: "decoy"},
+			{ProductId: "premium", AutoRenewProductId: "premium", AutoRenewStatus: AutoRenewStatusOn},
+			{
+				ProductId:                "basic",
+				AutoRenewProductId:       "basic",
+				OriginalTransactionId:    "1",
+				ExpirationIntent:         ExpirationIntentBillingIssue,
+				IsInBillingRetryPeriod:   BillingRetryStatusAttemptingRenewal,
+				GracePeriodExpiresDateMs: 2500,
+			},
+		},
+	}
+
+	subs := make(map[string]Subscription)
+	for _, sub := range resp.Subscriptions() {
+		subs[sub.OriginalTransactionId] = sub
+	}
+	if len(subs) != 2 {
+		t.Fatalf("want subscriptions 1 and 5, got %+v", subs)
+	}
+
+	basic := subs["1"]
+	if basic.TransactionId != "2" || !basic.ExpiresAt.Equal(timeFromMs(2000)) {
+		t.Errorf("want the latest transaction of the lineage, got %+v", basic)
+	}
+	if basic.AutoRenewProductId != "basic" {
+		t.Errorf("want the renewal info matched by original transaction, got %q", basic.AutoRenewProductId)
+	}
+	if !basic.IsInBillingRetryPeriod || basic.ExpirationIntent != ExpirationIntentBillingIssue || !basic.GracePeriodExpiresAt.Equal(timeFromMs(2500)) {
+		t.Errorf("want the billing retry and grace period fields, got %+v", basic)
+	}
+
+	premium := subs["5"]
+	if premium.AutoRenewProductId != "premium" || premium.AutoRenewStatus != AutoRenewStatusOn {
+		t.Errorf("want the renewal info matched by product, got %+v", premium)
+	}
+	if premium.IsInBillingRetryPeriod {
+		t.Errorf("want no billing retry, got %+v", premium)
+	}
+}
+
+func TestIsNewSubscriber(t *testing.T) {
+	tests := []struct {
+		name string
+		txs  []LatestReceiptInfo
+		want bool
+	}{
+		{
+			name: "first purchase",
+			txs: []LatestReceiptInfo{
+				{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 1000},
+			},
+			want: true,
+		},
+		{
+			name: "renewal",
+			txs: []LatestReceiptInfo{
+				{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 1000},
+				{ProductId: "basic", TransactionId: "2", OriginalTransactionId: "1", PurchaseDateMs: 1000, ExpiresDateMs: 2000},
+			},
+			want: false,
+		},
+		{
+			name: "resubscribe after a lapse",
+			txs: []LatestReceiptInfo{
+				{ProductId: "basic", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 1000},
+				{ProductId: "basic", TransactionId: "3", OriginalTransactionId: "1", PurchaseDateMs: 5000, ExpiresDateMs: 6000},
+			},
+			want: false,
+		},
+		{
+			name: "old transactions excluded",
+			txs: []LatestReceiptInfo{
+				{ProductId: "basic", TransactionId: "3", OriginalTransactionId: "1", PurchaseDateMs: 5000, ExpiresDateMs: 6000},
+			},
+			want: false,
+		},
+		{
+			name: "no transaction for the product",
+			txs: []LatestReceiptInfo{
+				{ProductId: "premium", TransactionId: "1", OriginalTransactionId: "1", PurchaseDateMs: 0, ExpiresDateMs: 1000},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &ReceiptResponse{LatestReceiptInfo: tt.txs}
+			if got := resp.IsNewSubscriber("basic"); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
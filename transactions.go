@@ -0,0 +1,119 @@
+package storekit
+
+import "sort"
+
+// ContainsTransaction reports whether the response holds the transaction with
+// the given identifier, either in the latest receipt info or in the in-app
+// purchases of the receipt.
+func (r *ReceiptResponse) ContainsTransaction(transactionID string) bool {
+	for _, tx := range r.sortedTransactions() {
+		if tx.TransactionId == transactionID {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTransactionsSince returns the transactions purchased after the one with
+// the given identifier, ordered by purchase date. When the response does not
+// hold the given transaction, every transaction is returned.
+func (r *ReceiptResponse) NewTransactionsSince(transactionID string) []InAppPurchaseReceipt {
+	txs := r.sortedTransactions()
+	for i, tx := range txs {
+		if tx.TransactionId != transactionID {
+			continue
+		}
+
+		var since []InAppPurchaseReceipt
+		for _, next := range txs[i+1:] {
+			if next.PurchaseDateMs > tx.PurchaseDateMs {
+				since = append(since, next)
+			}
+		}
+		return since
+	}
+
+	return txs
+}
+
+// SubscriptionLineage returns every transaction of the subscription with the
+// given original transaction identifier, ordered by purchase date: the
+// original purchase, its renewals, and the upgrades, downgrades and
+// resubscriptions that kept the identifier. A response verified excluding old
+// transactions may no longer hold the original purchase, or any earlier
+// period; the lineage then starts at the oldest transaction the response
+// holds, which can be told apart by its transaction identifier differing from
+// the original one.
+func (r *ReceiptResponse) SubscriptionLineage(originalTransactionID string) []InAppPurchaseReceipt {
+	var lineage []InAppPurchaseReceipt
+	for _, tx := range r.sortedTransactions() {
+		if tx.OriginalTransactionId == originalTransactionID {
+			lineage = append(lineage, tx)
+		}
+	}
+	return lineage
+}
+
+// UniqueTransactionIDs returns the identifier of every transaction of both the
+// latest receipt info and the receipt once, ordered by purchase date. Key
+// grants on these to never grant the same transaction twice.
+func (r *ReceiptResponse) UniqueTransactionIDs() []string {
+	txs := r.sortedTransactions()
+	ids := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, tx.TransactionId)
+	}
+	return ids
+}
+
+// DuplicateTransactionIDs returns the transaction identifiers that appear more
+// than once in the latest receipt info or more than once in the in-app
+// purchases of the receipt, in order of first appearance. A transaction listed
+// once in each is expected and not reported.
+func (r *ReceiptResponse) DuplicateTransactionIDs() []string {
+	var duplicates []string
+	reported := make(map[string]bool)
+	for _, txs := range [][]InAppPurchaseReceipt{fromLatestReceiptInfo(r.LatestReceiptInfo), r.Receipt.InApp} {
+		seen := make(map[string]bool, len(txs))
+		for _, tx := range txs {
+			if seen[tx.TransactionId] && !reported[tx.TransactionId] {
+				duplicates = append(duplicates, tx.TransactionId)
+				reported[tx.TransactionId] = true
+			}
+			seen[tx.TransactionId] = true
+		}
+	}
+	return duplicates
+}
+
+// sortedTransactions merges the latest receipt info with the in-app purchases
+// of the receipt, drops duplicated transaction identifiers in favor of the
+// latest receipt info, and orders the result by purchase date.
+func (r *ReceiptResponse) sortedTransactions() []InAppPurchaseReceipt {
+	txs := uniqueTransactions(append(fromLatestReceiptInfo(r.LatestReceiptInfo), r.Receipt.InApp...))
+	sortByPurchaseDate(txs)
+	return txs
+}
+
+// uniqueTransactions keeps the first occurrence of every transaction
+// identifier.
+func uniqueTransactions(txs []InAppPurchaseReceipt) []InAppPurchaseReceipt {
+	seen := make(map[string]bool, len(txs))
+	unique := txs[:0]
+	for _, tx := range txs {
+		if seen[tx.TransactionId] {
+			continue
+		}
+		seen[tx.TransactionId] = true
+		unique = append(unique, tx)
+	}
+	return unique
+}
+
+// sortByPurchaseDate orders the transactions chronologically, keeping the
+// given order for transactions purchased at the same time.
+func sortByPurchaseDate(txs []InAppPurchaseReceipt) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].PurchaseDateMs < txs[j].PurchaseDateMs
+	})
+}
@@ -0,0 +1,40 @@
+package storekit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// duplicateFixture lists transaction 1 once in each array, transaction 2 twice
+// in the latest receipt info and transactions 4 and 5 twice in the receipt.
+func duplicateFixture() *ReceiptResponse {
+	return &ReceiptResponse{
+		LatestReceiptInfo: []LatestReceiptInfo{
+			{ProductId: "basic", TransactionId: "1", PurchaseDateMs: 100},
+			{ProductId: "basic", TransactionId: "2", PurchaseDateMs: 300},
+			{ProductId: "basic", TransactionId: "2", PurchaseDateMs: 300},
+			{ProductId: "basic", TransactionId: "3", PurchaseDateMs: 50},
+		},
+		Receipt: Receipt{InApp: []InAppPurchaseReceipt{
+			{ProductId: "basic", TransactionId: "1", PurchaseDateMs: 100},
+			{ProductId: "coins", TransactionId: "4", PurchaseDateMs: 200},
+			{ProductId: "coins", TransactionId: "5", PurchaseDateMs: 150},
+			{ProductId: "coins", TransactionId: "4", PurchaseDateMs: 200},
+			{ProductId: "coins", TransactionId: "5", PurchaseDateMs: 150},
+		}},
+	}
+}
+
+func TestUniqueTransactionIDs(t *testing.T) {
+	got := duplicateFixture().UniqueTransactionIDs()
+	if want := []string{"3", "1", "5", "4", "2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestDuplicateTransactionIDs(t *testing.T) {
+	got := duplicateFixture().DuplicateTransactionIDs()
+	if want := []string{"2", "4", "5"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
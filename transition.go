@@ -0,0 +1,73 @@
+package storekit
+
+import "time"
+
+// TransitionType is how a subscription changed between two snapshots.
+type TransitionType string
+
+const (
+	// The subscription became active with no earlier snapshot of it.
+	TransitionNew TransitionType = "new"
+
+	// The subscription stayed active and its period was extended.
+	TransitionRenewed TransitionType = "renewed"
+
+	// The subscription was active and no longer is.
+	TransitionChurned TransitionType = "churned"
+
+	// The subscription was inactive and is active again, on the same or another
+	// product.
+	TransitionReactivated TransitionType = "reactivated"
+
+	// The subscription stayed active and moved to a higher ranked product.
+	TransitionUpgraded TransitionType = "upgraded"
+
+	// The subscription stayed active and moved to a lower ranked product.
+	TransitionDowngraded TransitionType = "downgraded"
+
+	// Nothing that matters changed.
+	TransitionUnchanged TransitionType = "unchanged"
+)
+
+// ClassifyTransition compares two snapshots of a subscription, taken at prevAt
+// and curAt, typically from successive verifications. A nil snapshot stands
+// for a subscription absent from the response. Whether each snapshot is
+// active is evaluated at its own time, so a period that lapsed between them
+// counts as churn even when the product and expiry did not change.
+//
+// The rules apply in order:
+//   - inactive to active is a reactivation, or new when there is no previous
+//     snapshot;
+//   - active to inactive or absent is churn;
+//   - while active, a change of product is an upgrade or a downgrade as told
+//     by the ranking; a crossgrade, or any change without a ranking, falls
+//     through to the next rule;
+//   - while active, a later expiry is a renewal;
+//   - anything else is unchanged.
+func ClassifyTransition(prev, cur *Subscription, prevAt, curAt time.Time, rank ProductRanking) TransitionType {
+	prevActive := prev != nil && prev.IsActive(prevAt)
+	curActive := cur != nil && cur.IsActive(curAt)
+
+	switch {
+	case !prevActive && curActive && prev == nil:
+		return TransitionNew
+	case !prevActive && curActive:
+		return TransitionReactivated
+	case prevActive && !curActive:
+		return TransitionChurned
+	case !curActive:
+		return TransitionUnchanged
+	}
+
+	switch compareProducts(prev.ProductId, cur.ProductId, rank) {
+	case ChangeKindUpgrade:
+		return TransitionUpgraded
+	case ChangeKindDowngrade:
+		return TransitionDowngraded
+	}
+	if cur.ExpiresAt.After(prev.ExpiresAt) {
+		return TransitionRenewed
+	}
+
+	return TransitionUnchanged
+}
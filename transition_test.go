@@ -0,0 +1,49 @@
+package storekit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyTransition(t *testing.T) {
+	day := 24 * time.Hour
+	prevAt := time.Unix(1600000000, 0)
+	curAt := prevAt.Add(30 * day)
+	ranks := map[string]int{"basic": 1, "premium": 2, "premium_yearly": 2}
+	rank := func(productID string) int { return ranks[productID] }
+
+	sub := func(productID string, expiresAt time.Time) *Subscription {
+		return &Subscription{ProductId: productID, OriginalTransactionId: "1", ExpiresAt: expiresAt}
+	}
+
+	tests := []struct {
+		name string
+		prev *Subscription
+		cur  *Subscription
+		rank ProductRanking
+		want TransitionType
+	}{
+		{"absent both times", nil, nil, rank, TransitionUnchanged},
+		{"first active snapshot", nil, sub("basic", curAt.Add(day)), rank, TransitionNew},
+		{"first snapshot already expired", nil, sub("basic", curAt.Add(-day)), rank, TransitionUnchanged},
+		{"renewed", sub("basic", prevAt.Add(day)), sub("basic", curAt.Add(day)), rank, TransitionRenewed},
+		{"still in the same period", sub("basic", curAt.Add(day)), sub("basic", curAt.Add(day)), rank, TransitionUnchanged},
+		{"lapsed without change", sub("basic", prevAt.Add(day)), sub("basic", prevAt.Add(day)), rank, TransitionChurned},
+		{"disappeared", sub("basic", prevAt.Add(day)), nil, rank, TransitionChurned},
+		{"refunded", sub("basic", curAt.Add(day)), &Subscription{ProductId: "basic", ExpiresAt: curAt.Add(day), CancelledAt: curAt.Add(-day)}, rank, TransitionChurned},
+		{"resubscribed after lapse", sub("basic", prevAt.Add(-day)), sub("basic", curAt.Add(day)), rank, TransitionReactivated},
+		{"resubscribed to another product", sub("basic", prevAt.Add(-day)), sub("premium", curAt.Add(day)), rank, TransitionReactivated},
+		{"stayed expired", sub("basic", prevAt.Add(-day)), sub("basic", prevAt.Add(-day)), rank, TransitionUnchanged},
+		{"upgraded", sub("basic", prevAt.Add(day)), sub("premium", curAt.Add(day)), rank, TransitionUpgraded},
+		{"downgraded", sub("premium", prevAt.Add(day)), sub("basic", curAt.Add(day)), rank, TransitionDowngraded},
+		{"crossgraded and renewed", sub("premium", prevAt.Add(day)), sub("premium_yearly", curAt.Add(300*day)), rank, TransitionRenewed},
+		{"product change without ranking", sub("basic", prevAt.Add(day)), sub("premium", curAt.Add(day)), nil, TransitionRenewed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyTransition(tt.prev, tt.cur, prevAt, curAt, tt.rank); got != tt.want {
+				t.Fatalf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package storekit
+
+import "time"
+
+// RemainingTrialPeriod returns the time left until the free trial or
+// introductory price period of the subscription to the given product converts
+// to a regular paid period. It returns false when the subscription is not in
+// such a period at the given time.
+func (r *ReceiptResponse) RemainingTrialPeriod(productID string, at time.Time) (time.Duration, bool) {
+	tx, ok := latestTransaction(r.transactions(), productID)
+	if !ok || !isIntroductory(tx) || tx.CancellationDateMs != 0 {
+		return 0, false
+	}
+
+	expiresAt := timeFromMs(tx.ExpiresDateMs)
+	if !at.Before(expiresAt) {
+		return 0, false
+	}
+
+	return expiresAt.Sub(at), true
+}
+
+// TrialConverted returns when the subscription to the given product converted
+// from its free trial to a paid period: the purchase date of the first
+// transaction of the subscription that follows a trial and is not a trial
+// itself. Introductory price periods count as paid. It returns false when the
+// subscription never had a trial, is still in it, or lapsed without paying.
+// Pass a response verified without excluding old transactions, as the trial
+// is otherwise missing from the history.
+func (r *ReceiptResponse) TrialConverted(productID string) (convertedAt time.Time, ok bool) {
+	latest, ok := latestTransaction(r.transactions(), productID)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	trial := false
+	for _, tx := range r.SubscriptionLineage(latest.OriginalTransactionId) {
+		switch {
+		case tx.IsTrialPeriod.Bool():
+			trial = true
+		case trial:
+			return timeFromMs(tx.PurchaseDateMs), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// isIntroductory reports whether the transaction pays for a free trial or an
+// introductory price period.
+func isIntroductory(tx InAppPurchaseReceipt) bool {
+	return tx.IsTrialPeriod.Bool() || tx.IsInIntroOfferPeriod.Bool()
+}
@@ -0,0 +1,93 @@
+package storekit
+
+import (
+	"context"
+	"time"
+)
+
+// VerifyResult gathers the outcome of a single verification.
+type VerifyResult struct {
+	// The parsed App Store response. Nil when the request failed before the
+	// App Store answered or the answer could not be decoded, so check it, or
+	// OK, before use.
+	Response *ReceiptResponse
+
+	// The raw response body as returned by the App Store.
+	Body []byte
+
+	// The correlation identifier carried by the context of the verification.
+	RequestID string
+
+	// The metadata carried by the context of the verification, without the
+	// redacted keys.
+	Metadata map[string]string
+
+	// The environment that served the final response.
+	Environment Environment
+
+	// The number of requests sent to the App Store, including the resend made by
+	// auto fix.
+	Attempts int
+
+	// The error the verification ended with. A non-zero response status is
+	// reported as a *StatusError.
+	Err error
+}
+
+// OK reports whether the verification succeeded: the App Store answered with
+// an accepted status and every client-side check passed. Accepted statuses are
+// zero and, with WithExpiredSubscriptionAsValid, 21006. Response is never nil
+// when OK returns true.
+func (r *VerifyResult) OK() bool {
+	return r.Err == nil
+}
+
+// DetailedResult gathers the outcome of a verification along with the typed
+// views derived from the response.
+type DetailedResult struct {
+	*VerifyResult
+
+	// The time of the client clock the views were evaluated at.
+	EvaluatedAt time.Time
+
+	// The normalized view of the response. Nil when the request failed.
+	Normalized *NormalizedResponse
+
+	// The auto-renewable subscriptions of the response.
+	Subscriptions []Subscription
+
+	// The entitlement of every product of the response, keyed by product
+	// identifier.
+	Entitlements map[string]*Entitlement
+}
+
+// VerifyDetailed verifies the receipt like VerifyWithResult and, when the App
+// Store returned a response, derives its normalized, subscription and
+// entitlement views as of the time of the client clock. The views are derived
+// even for a response with a non-zero status, which the error of the result
+// reports. Use Verify when the views are not needed.
+func (c *client) VerifyDetailed(ctx context.Context, receiptRequest *ReceiptRequest) *DetailedResult {
+	result := &DetailedResult{
+		VerifyResult: c.VerifyWithResult(ctx, receiptRequest),
+		EvaluatedAt:  c.clock.Now(),
+	}
+
+	resp := result.Response
+	if resp == nil {
+		return result
+	}
+
+	result.Normalized = resp.Normalized()
+	result.Subscriptions = resp.Subscriptions()
+	result.Entitlements = make(map[string]*Entitlement)
+	for _, tx := range resp.transactions() {
+		if _, ok := result.Entitlements[tx.ProductId]; ok {
+			continue
+		}
+		if e, ok := resp.Entitlement(tx.ProductId, result.EvaluatedAt); ok {
+			result.Entitlements[tx.ProductId] = e
+		}
+	}
+
+	return result
+}